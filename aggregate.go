@@ -0,0 +1,263 @@
+// Defines AggregateQuery struct for computing counts and summary statistics on the server
+
+package dbIO
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Filter stores a condition for GroupCount. Op may be any operator accepted by SelectQuery.Where.
+type Filter struct {
+	Column string
+	Op     string
+	Value  interface{}
+}
+
+// Group stores the result of an aggregate function for one group of rows.
+type Group struct {
+	// Keys are the values of the GroupBy columns, in order. NULL values are stored as DBIO.NullValue.
+	Keys []string
+	// Count is the number of rows in the group.
+	Count int64
+	// Value is the result of the aggregate function. It is equal to Count for AggregateQuery.Count.
+	Value float64
+	// Null is true if Value is NULL (i.e. the column contains only NULL values in the group).
+	Null bool
+}
+
+// having stores a single predicate in a HAVING clause.
+type having struct {
+	op    string
+	value interface{}
+}
+
+// AggregateQuery builds a statement which computes an aggregate function over a table, optionally grouped by one or more
+// columns. Create one with DBIO.Aggregate. Identifiers are validated when the statement is submitted.
+type AggregateQuery struct {
+	s      *SelectQuery
+	groups []string
+	having []having
+	top    int
+}
+
+// Aggregate starts an aggregate query over table.
+func (d *DBIO) Aggregate(table string) *AggregateQuery {
+	return &AggregateQuery{s: d.Select().From(table), top: -1}
+}
+
+// Where adds a condition which rows must meet to be included. It accepts the same operators as SelectQuery.Where and is
+// equivalent to And.
+func (a *AggregateQuery) Where(column, op string, value interface{}) *AggregateQuery {
+	a.s.And(column, op, value)
+	return a
+}
+
+// And adds a condition which must be true in addition to the previous conditions.
+func (a *AggregateQuery) And(column, op string, value interface{}) *AggregateQuery {
+	a.s.And(column, op, value)
+	return a
+}
+
+// Or adds a condition which may be true instead of the previous conditions.
+func (a *AggregateQuery) Or(column, op string, value interface{}) *AggregateQuery {
+	a.s.Or(column, op, value)
+	return a
+}
+
+// GroupBy computes the aggregate separately for each combination of values in the given columns.
+func (a *AggregateQuery) GroupBy(columns ...string) *AggregateQuery {
+	a.groups = append(a.groups, columns...)
+	return a
+}
+
+// Having only returns groups where the result of the aggregate function relates to value via op (>=/=/...; ie. COUNT(*) >= 7).
+func (a *AggregateQuery) Having(op string, value interface{}) *AggregateQuery {
+	a.having = append(a.having, having{op, value})
+	return a
+}
+
+// Top only returns the n most frequent groups, ordered by decreasing count.
+func (a *AggregateQuery) Top(n int) *AggregateQuery {
+	a.top = n
+	return a
+}
+
+// Returns the statement and arguments for the given aggregate expression.
+func (a *AggregateQuery) sql(ctx context.Context, fn, column string) (string, []interface{}, error) {
+	tbl, err := a.s.d.checkTable(ctx, a.s.table)
+	if err != nil {
+		return "", nil, err
+	}
+	expr := "COUNT(*)"
+	if len(column) > 0 {
+		col, err := a.s.column(ctx, column)
+		if err != nil {
+			return "", nil, err
+		}
+		expr = fmt.Sprintf("%s(%s)", fn, col)
+	}
+	var groups []string
+	for _, i := range a.groups {
+		col, err := a.s.column(ctx, i)
+		if err != nil {
+			return "", nil, err
+		}
+		groups = append(groups, col)
+	}
+	where, args, err := a.s.whereClause(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	var b strings.Builder
+	b.WriteString("SELECT ")
+	for _, i := range groups {
+		b.WriteString(i + ",")
+	}
+	b.WriteString("COUNT(*)")
+	if expr != "COUNT(*)" {
+		b.WriteString("," + expr)
+	}
+	b.WriteString(" FROM " + tbl + where)
+	if len(groups) > 0 {
+		b.WriteString(" GROUP BY " + strings.Join(groups, ","))
+	}
+	for idx, i := range a.having {
+		op, err := checkOperator(i.op)
+		if err != nil {
+			return "", nil, err
+		}
+		if idx == 0 {
+			b.WriteString(" HAVING ")
+		} else {
+			b.WriteString(" AND ")
+		}
+		b.WriteString(fmt.Sprintf("%s %s ?", expr, op))
+		args = append(args, i.value)
+	}
+	if a.top >= 0 {
+		b.WriteString(" ORDER BY COUNT(*) DESC")
+		for _, i := range groups {
+			// Break ties so the result is deterministic
+			b.WriteString(", " + i)
+		}
+		b.WriteString(" LIMIT ?")
+		args = append(args, a.top)
+	}
+	return b.String() + ";", args, nil
+}
+
+// Submits the statement and converts the results to groups.
+func (a *AggregateQuery) run(ctx context.Context, fn, column string) ([]Group, error) {
+	cmd, args, err := a.sql(ctx, fn, column)
+	if err != nil {
+		return nil, fmt.Errorf("formatting aggregate query for %s: %w", a.s.table, err)
+	}
+	res, err := a.s.d.queryResult(ctx, cmd, args)
+	if err != nil {
+		return nil, err
+	}
+	n := len(a.groups)
+	ret := make([]Group, 0, len(res.Rows))
+	for idx, row := range res.Rows {
+		g := Group{Keys: row[:n]}
+		if g.Count, err = strconv.ParseInt(row[n], 10, 64); err != nil {
+			return nil, fmt.Errorf("reading count from '%s': %w", cmd, err)
+		}
+		if len(row) == n+1 {
+			g.Value = float64(g.Count)
+		} else if g.Null = res.nulls[idx][n+1]; !g.Null {
+			if g.Value, err = strconv.ParseFloat(row[n+1], 64); err != nil {
+				return nil, fmt.Errorf("reading %s of %s from '%s': %w", fn, column, cmd, err)
+			}
+		}
+		ret = append(ret, g)
+	}
+	return ret, nil
+}
+
+// CountCtx returns the number of rows in each group.
+func (a *AggregateQuery) CountCtx(ctx context.Context) ([]Group, error) {
+	return a.run(ctx, "COUNT", "")
+}
+
+// Count returns the number of rows in each group.
+func (a *AggregateQuery) Count() ([]Group, error) {
+	return a.CountCtx(context.Background())
+}
+
+// SumCtx returns the sum of column for each group.
+func (a *AggregateQuery) SumCtx(ctx context.Context, column string) ([]Group, error) {
+	return a.run(ctx, "SUM", column)
+}
+
+// Sum returns the sum of column for each group.
+func (a *AggregateQuery) Sum(column string) ([]Group, error) {
+	return a.SumCtx(context.Background(), column)
+}
+
+// AvgCtx returns the mean of column for each group.
+func (a *AggregateQuery) AvgCtx(ctx context.Context, column string) ([]Group, error) {
+	return a.run(ctx, "AVG", column)
+}
+
+// Avg returns the mean of column for each group.
+func (a *AggregateQuery) Avg(column string) ([]Group, error) {
+	return a.AvgCtx(context.Background(), column)
+}
+
+// MinCtx returns the minimum value of column for each group.
+func (a *AggregateQuery) MinCtx(ctx context.Context, column string) ([]Group, error) {
+	return a.run(ctx, "MIN", column)
+}
+
+// Min returns the minimum value of column for each group.
+func (a *AggregateQuery) Min(column string) ([]Group, error) {
+	return a.MinCtx(context.Background(), column)
+}
+
+// MaxCtx returns the maximum value of column for each group.
+func (a *AggregateQuery) MaxCtx(ctx context.Context, column string) ([]Group, error) {
+	return a.run(ctx, "MAX", column)
+}
+
+// Max returns the maximum value of column for each group.
+func (a *AggregateQuery) Max(column string) ([]Group, error) {
+	return a.MaxCtx(context.Background(), column)
+}
+
+// StdDevCtx returns the sample standard deviation of column for each group. Value is NULL for groups with fewer than two
+// non-NULL values.
+func (a *AggregateQuery) StdDevCtx(ctx context.Context, column string) ([]Group, error) {
+	return a.run(ctx, "STDDEV_SAMP", column)
+}
+
+// StdDev returns the sample standard deviation of column for each group.
+func (a *AggregateQuery) StdDev(column string) ([]Group, error) {
+	return a.StdDevCtx(context.Background(), column)
+}
+
+// GroupCountCtx returns a map with the number of rows for each value in column, counted by the server. Only rows matching all of
+// the given filters are counted.
+func (d *DBIO) GroupCountCtx(ctx context.Context, table, column string, filters ...Filter) (map[string]int, error) {
+	a := d.Aggregate(table).GroupBy(column)
+	for _, i := range filters {
+		a.Where(i.Column, i.Op, i.Value)
+	}
+	groups, err := a.CountCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[string]int, len(groups))
+	for _, i := range groups {
+		ret[i.Keys[0]] += int(i.Count)
+	}
+	return ret, nil
+}
+
+// GroupCount returns a map with the number of rows for each value in column, counted by the server.
+func (d *DBIO) GroupCount(table, column string, filters ...Filter) (map[string]int, error) {
+	return d.GroupCountCtx(context.Background(), table, column, filters...)
+}
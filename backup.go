@@ -3,6 +3,7 @@
 package dbIO
 
 import (
+	"context"
 	"fmt"
 	"os/exec"
 	"path/filepath"
@@ -22,25 +23,64 @@ func (d *DBIO) getBackupFile(outdir string) string {
 	return fmt.Sprintf("--result-file=%s.%s.sql", name, datestamp)
 }
 
-func (d *DBIO) getHost() string {
-	// Returns formatted host name
-	host := d.Host[:strings.Index(d.Host, ":")]
-	host = host[strings.Index(host, "(")+1:]
-	return fmt.Sprintf("-h%s", host)
+func (d *DBIO) dumpConfig() *Config {
+	// Returns Config, or a Config parsed from Host if it is not set
+	if d.Config != nil {
+		return d.Config
+	}
+	// Host may be formatted as the server's address (e.g. tcp(localhost:3306))
+	host := d.Host
+	if i := strings.Index(host, "("); i >= 0 && strings.HasSuffix(host, ")") {
+		host = host[i+1 : len(host)-1]
+	}
+	return NewConfig(host, d.Database, d.User, d.Password)
+}
+
+func (d *DBIO) getHost() []string {
+	// Returns mysqldump arguments for the host and port or socket
+	c := d.dumpConfig()
+	if len(c.Socket) > 0 {
+		return []string{fmt.Sprintf("--socket=%s", c.Socket)}
+	}
+	host := c.Host
+	if len(host) < 1 {
+		host = "localhost"
+	}
+	ret := []string{fmt.Sprintf("-h%s", host)}
+	if c.Port > 0 {
+		ret = append(ret, fmt.Sprintf("-P%d", c.Port))
+	}
+	return ret
+}
+
+// BackupDBCtx calls mysldump to back up database to local machine. The mysqldump process is killed if ctx is cancelled.
+func (d *DBIO) BackupDBCtx(ctx context.Context, outdir string) error {
+	d.logger.Printf("Backing up %s database to local machine...\n", d.Database)
+	args := []string{fmt.Sprintf("-u%s", d.User)}
+	args = append(args, d.getHost()...)
+	args = append(args, d.dumpConfig().dumpTLSArgs()...)
+	args = append(args, fmt.Sprintf("-p%s", d.Password), d.getBackupFile(outdir), d.Database, "--column-statistics=0")
+	bu := exec.CommandContext(ctx, "mysqldump", args...)
+	if err := bu.Run(); err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return fmt.Errorf("backing up %s: %w", d.Database, err)
+	}
+	d.logger.Println("Backup complete.")
+	return nil
+}
+
+// BackupDBE calls mysldump to back up database to local machine
+func (d *DBIO) BackupDBE(outdir string) error {
+	return d.BackupDBCtx(context.Background(), outdir)
 }
 
 // BackupDB calls mysldump to back up database to local machine
+//
+// Deprecated: Use BackupDBE, which returns errors instead of logging them.
 func (d *DBIO) BackupDB(outdir string) {
-	d.logger.Printf("Backing up %s database to local machine...\n", d.Database)
-	user := fmt.Sprintf("-u%s", d.User)
-	host := d.getHost()
-	password := fmt.Sprintf("-p%s", d.Password)
-	outfile := d.getBackupFile(outdir)
-	bu := exec.Command("mysqldump", user, host, password, outfile, d.Database, "--column-statistics=0")
-	err := bu.Run()
-	if err == nil {
-		d.logger.Println("Backup complete.")
-	} else {
+	if err := d.BackupDBE(outdir); err != nil {
 		d.logger.Printf("Backup failed. %v\n", err)
 	}
 }
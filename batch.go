@@ -0,0 +1,475 @@
+// Contains functions for uploading rows with parameterized INSERT statements
+
+package dbIO
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const (
+	// The maximum number of ? placeholders MySQL accepts in a single prepared statement.
+	maxPlaceholders = 65535
+	// The max_allowed_packet assumed if it cannot be read from the server (the default for MySQL 5.7).
+	defaultMaxPacket = 4 << 20
+)
+
+// InsertMode determines how rows which duplicate an existing primary or unique key are handled.
+type InsertMode int
+
+const (
+	// ModeInsert fails if a row duplicates an existing key.
+	ModeInsert InsertMode = iota
+	// ModeIgnore skips rows which duplicate an existing key (INSERT IGNORE). Note that MySQL also converts some other errors, such
+	// as values which are out of range for their column, to warnings in this mode.
+	ModeIgnore
+	// ModeReplace deletes existing rows with duplicate keys before inserting the new rows (REPLACE INTO).
+	ModeReplace
+	// ModeUpsert updates existing rows with duplicate keys using the new values (INSERT ... ON DUPLICATE KEY UPDATE).
+	ModeUpsert
+)
+
+// UploadOptions stores options for Upload.
+type UploadOptions struct {
+	// Mode determines how rows with duplicate keys are handled.
+	Mode InsertMode
+	// Update lists the columns which are set to the new values by ModeUpsert. If it is empty, all columns which are not part of a
+	// primary or unique key are updated.
+	Update []string
+	// Columns lists the columns of the table in the order their values appear in each row. If it is empty, each row holds a value
+	// for every column of the table in the order of DBIO.Columns.
+	Columns []string
+	// Header indicates that the first row names the column of each value, in any order. Names are not case sensitive and the
+	// header row is not uploaded. Columns is ignored if Header is set.
+	Header bool
+	// SkipDefaults omits AUTO_INCREMENT and generated columns and columns with a default value if no columns are given by Columns
+	// or Header, so each row only holds values for the remaining columns and the server assigns the rest.
+	SkipDefaults bool
+	// Null is the value which is inserted as NULL (e.g. NullNA). Every value is inserted unchanged if it is empty.
+	Null string
+}
+
+// RowWidthError is returned when a row does not have one value for each column being uploaded. It wraps ErrInvalidQuery. Rows
+// are checked before any are uploaded.
+type RowWidthError struct {
+	Table string
+	// Row is the index of the row in the input, including the header row if there is one.
+	Row int
+	// Values is the number of values in the row.
+	Values int
+	// Columns is the number of columns being uploaded.
+	Columns int
+}
+
+// Error returns the index and width of the row.
+func (e *RowWidthError) Error() string {
+	return fmt.Sprintf("%v: row %d has %d values but %d columns are being uploaded to %s", ErrInvalidQuery, e.Row, e.Values, e.Columns, e.Table)
+}
+
+// Unwrap returns ErrInvalidQuery.
+func (e *RowWidthError) Unwrap() error {
+	return ErrInvalidQuery
+}
+
+// Returns a RowWidthError for the first row which does not have width values. Rows are numbered from start.
+func checkWidths(table string, rows [][]string, width, start int) error {
+	for idx, i := range rows {
+		if len(i) != width {
+			return &RowWidthError{Table: table, Row: start + idx, Values: len(i), Columns: width}
+		}
+	}
+	return nil
+}
+
+// ChunkResult stores the number of rows handled by a single INSERT statement. Counts are derived from the number of affected rows
+// reported by MySQL. In ModeReplace, Updated is the number of rows which replaced an existing row. In ModeUpsert, duplicate rows
+// whose values did not change are counted as Ignored; the counts are exact unless a statement contains both updated rows and
+// unchanged duplicates, in which case some updated rows are counted as inserted.
+type ChunkResult struct {
+	Rows     int
+	Inserted int
+	Updated  int
+	Ignored  int
+}
+
+// UploadResult stores the total counts for an upload and the counts for each statement.
+type UploadResult struct {
+	Rows     int
+	Inserted int
+	Updated  int
+	Ignored  int
+	Chunks   []ChunkResult
+}
+
+// Adds the counts of c to the totals.
+func (r *UploadResult) add(c ChunkResult) {
+	r.Rows += c.Rows
+	r.Inserted += c.Inserted
+	r.Updated += c.Updated
+	r.Ignored += c.Ignored
+	r.Chunks = append(r.Chunks, c)
+}
+
+// insertStatement stores the parts of a multi-row INSERT statement which do not depend on the rows.
+type insertStatement struct {
+	table  string
+	prefix string
+	suffix string
+	width  int
+	mode   InsertMode
+	null   string
+}
+
+// packetCache stores the server's max_allowed_packet once it has been read.
+type packetCache struct {
+	sync.Mutex
+	size int
+}
+
+// Returns the maximum size of a statement in bytes. DBIO.MaxPacket is returned if it is set. Otherwise @@max_allowed_packet is
+// read from the server once per connection, and limited to the driver's MaxAllowedPacket.
+func (d *DBIO) maxPacket(ctx context.Context) int {
+	if d.MaxPacket > 0 {
+		return d.MaxPacket
+	}
+	if d.packet != nil {
+		d.packet.Lock()
+		defer d.packet.Unlock()
+		if d.packet.size > 0 {
+			return d.packet.size
+		}
+	}
+	var size int
+	if err := d.conn().QueryRowContext(ctx, "SELECT @@max_allowed_packet;").Scan(&size); err != nil || size <= 0 {
+		// Do not cache the default so the value is read once the server is reachable
+		return defaultMaxPacket
+	}
+	if d.Config != nil && d.Config.Config != nil && d.Config.MaxAllowedPacket > 0 && d.Config.MaxAllowedPacket < size {
+		// The driver rejects larger packets before they are sent
+		size = d.Config.MaxAllowedPacket
+	}
+	if d.packet != nil {
+		d.packet.size = size
+	}
+	return size
+}
+
+// Returns the number of bytes v adds to a statement. Values are sent with a length prefix and type, or as escaped, quoted strings
+// if the driver interpolates parameters.
+func (d *DBIO) encodedSize(v string) int {
+	if d.Config != nil && d.Config.Config != nil && d.Config.InterpolateParams {
+		n := len(v) + 3
+		for i := 0; i < len(v); i++ {
+			switch v[i] {
+			case 0, '\n', '\r', '\\', '\'', '"', '\x1a':
+				n++
+			}
+		}
+		return n
+	}
+	return len(v) + 11
+}
+
+// Returns the columns of table which are part of a primary or unique key.
+func (d *DBIO) uniqueColumns(ctx context.Context, table string) (map[string]bool, error) {
+	cmd := "SELECT COLUMN_NAME FROM information_schema.STATISTICS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND NON_UNIQUE = 0;"
+	rows, err := d.ExecuteCtx(ctx, cmd, table)
+	if err != nil {
+		return nil, fmt.Errorf("extracting unique keys of %s: %w", table, err)
+	}
+	ret := make(map[string]bool)
+	for _, i := range rows {
+		ret[strings.ToLower(i[0])] = true
+	}
+	return ret, nil
+}
+
+// Returns the columns of table which are assigned by the server if they are omitted from an insert.
+func (d *DBIO) defaultColumns(ctx context.Context, table string) (map[string]bool, error) {
+	cmd := "SELECT COLUMN_NAME FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND " +
+		"(COLUMN_DEFAULT IS NOT NULL OR EXTRA LIKE '%auto_increment%' OR EXTRA LIKE '%GENERATED%');"
+	rows, err := d.ExecuteCtx(ctx, cmd, table)
+	if err != nil {
+		return nil, fmt.Errorf("extracting default columns of %s: %w", table, err)
+	}
+	ret := make(map[string]bool)
+	for _, i := range rows {
+		ret[strings.ToLower(i[0])] = true
+	}
+	return ret, nil
+}
+
+// Returns the columns being uploaded and the number of header rows to skip.
+func (d *DBIO) uploadColumns(ctx context.Context, table string, rows [][]string, opts UploadOptions) ([]string, int, error) {
+	names, header := opts.Columns, 0
+	if opts.Header {
+		if len(rows) == 0 {
+			return nil, 0, fmt.Errorf("%w: header row is missing", ErrInvalidQuery)
+		}
+		names, header = rows[0], 1
+	}
+	if len(names) == 0 {
+		columns, err := d.tableColumns(ctx, table)
+		if err != nil {
+			return nil, 0, err
+		}
+		var skip map[string]bool
+		if opts.SkipDefaults {
+			if skip, err = d.defaultColumns(ctx, table); err != nil {
+				return nil, 0, err
+			}
+		}
+		var ret []string
+		for _, c := range columns {
+			if c = strings.TrimSpace(c); !skip[strings.ToLower(c)] {
+				ret = append(ret, c)
+			}
+		}
+		if len(ret) == 0 {
+			return nil, 0, fmt.Errorf("%w: every column of %s has a default value", ErrInvalidQuery, table)
+		}
+		return ret, 0, nil
+	}
+	ret := make([]string, len(names))
+	seen := make(map[string]bool)
+	for i, c := range names {
+		name, err := d.columnName(ctx, table, c)
+		if err != nil {
+			return nil, 0, err
+		}
+		ret[i] = strings.TrimSpace(name)
+		key := strings.ToLower(ret[i])
+		if seen[key] {
+			return nil, 0, fmt.Errorf("%w: column %q is given more than once", ErrInvalidQuery, ret[i])
+		}
+		seen[key] = true
+	}
+	return ret, header, nil
+}
+
+// Returns the ON DUPLICATE KEY UPDATE clause for the given update columns, or for all non-key columns if none are given.
+func (d *DBIO) upsertClause(ctx context.Context, table string, columns, update []string) (string, error) {
+	if len(update) == 0 {
+		keys, err := d.uniqueColumns(ctx, table)
+		if err != nil {
+			return "", err
+		}
+		for _, i := range columns {
+			if !keys[strings.ToLower(i)] {
+				update = append(update, i)
+			}
+		}
+		if len(update) == 0 {
+			// Every column is part of a key, so duplicates are left unchanged
+			update = columns[:1]
+		}
+	}
+	terms := make([]string, len(update))
+	for i, c := range update {
+		col, err := d.checkColumn(ctx, table, c)
+		if err != nil {
+			return "", err
+		}
+		// VALUES() is deprecated in MySQL 8.0.20 in favour of row aliases, but aliases are not supported by older servers
+		terms[i] = fmt.Sprintf("%s = VALUES(%s)", col, col)
+	}
+	return " ON DUPLICATE KEY UPDATE " + strings.Join(terms, ","), nil
+}
+
+// Returns an INSERT statement for the given columns of table.
+func (d *DBIO) newInsertStatement(ctx context.Context, table string, columns []string, opts UploadOptions) (*insertStatement, error) {
+	tbl, err := d.checkTable(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = quoteIdentifier(c)
+	}
+	ret := &insertStatement{table: table, width: len(columns), mode: opts.Mode, null: opts.Null}
+	verb := "INSERT INTO"
+	switch opts.Mode {
+	case ModeInsert:
+	case ModeIgnore:
+		verb = "INSERT IGNORE INTO"
+	case ModeReplace:
+		verb = "REPLACE INTO"
+	case ModeUpsert:
+		if ret.suffix, err = d.upsertClause(ctx, table, columns, opts.Update); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown insert mode %d", ErrInvalidQuery, opts.Mode)
+	}
+	ret.prefix = fmt.Sprintf("%s %s (%s) VALUES ", verb, tbl, strings.Join(quoted, ","))
+	return ret, nil
+}
+
+// Returns the counts for a statement with the given number of rows and affected rows.
+func (s *insertStatement) result(rows int, affected int64) ChunkResult {
+	ret := ChunkResult{Rows: rows}
+	n := int(affected)
+	switch s.mode {
+	case ModeIgnore:
+		ret.Inserted = n
+		ret.Ignored = rows - n
+	case ModeReplace:
+		// Each replaced row counts as a deletion and an insertion
+		ret.Updated = n - rows
+		ret.Inserted = rows - ret.Updated
+	case ModeUpsert:
+		// Inserted rows count as 1, updated rows as 2, and unchanged rows as 0
+		if n >= rows {
+			ret.Updated = n - rows
+			ret.Inserted = rows - ret.Updated
+		} else {
+			ret.Inserted = n
+			ret.Ignored = rows - n
+		}
+	default:
+		ret.Inserted = n
+	}
+	return ret
+}
+
+// Returns the number of rows from the start of rows to include in the next statement. Chunks are limited by the number of
+// placeholders and by the encoded size of the statement, which is kept at least 10% below max_allowed_packet.
+func (d *DBIO) chunkSize(s *insertStatement, rows [][]string, packet int) int {
+	limit := maxPlaceholders / s.width
+	budget := packet - packet/10
+	// Each row adds its placeholders to the statement text
+	size := len(s.prefix) + len(s.suffix) + 1
+	for idx, row := range rows {
+		if idx == limit {
+			return idx
+		}
+		size += 2*s.width + 2
+		for _, v := range row {
+			size += d.encodedSize(v)
+		}
+		if size > budget && idx > 0 {
+			return idx
+		}
+	}
+	return len(rows)
+}
+
+// Returns row as statement arguments. Values equal to null are inserted as NULL if null is not empty.
+func rowArgs(args []interface{}, row []string, null string) []interface{} {
+	for _, v := range row {
+		if len(null) > 0 && v == null {
+			args = append(args, nil)
+		} else {
+			args = append(args, v)
+		}
+	}
+	return args
+}
+
+// Submits a single multi-row INSERT statement.
+func (d *DBIO) insertChunk(ctx context.Context, s *insertStatement, rows [][]string) (ChunkResult, error) {
+	var b strings.Builder
+	var affected int64
+	args := make([]interface{}, 0, len(rows)*s.width)
+	row := "(" + placeholders(s.width) + ")"
+	b.WriteString(s.prefix)
+	for idx, i := range rows {
+		if idx > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(row)
+		args = rowArgs(args, i, s.null)
+	}
+	b.WriteString(s.suffix + ";")
+	cmd := b.String()
+	err := d.retry(ctx, func() error {
+		res, err := d.conn().ExecContext(ctx, cmd, args...)
+		if err == nil {
+			affected, err = res.RowsAffected()
+		}
+		return newError("uploading to", s.table, err)
+	})
+	return s.result(len(rows), affected), err
+}
+
+// Submits rows in a single statement. If the statement exceeds max_allowed_packet, rows are split in half and each half is
+// submitted separately. The counts for each statement are added to res.
+func (d *DBIO) insertSplit(ctx context.Context, s *insertStatement, rows [][]string, res *UploadResult) error {
+	c, err := d.insertChunk(ctx, s, rows)
+	if err == nil {
+		res.add(c)
+		return nil
+	} else if !IsPacketTooLarge(err) || len(rows) < 2 {
+		return err
+	}
+	half := len(rows) / 2
+	if err = d.insertSplit(ctx, s, rows[:half], res); err != nil {
+		return err
+	}
+	return d.insertSplit(ctx, s, rows[half:], res)
+}
+
+// Uploads rows in chunks and calls progress with the number of rows uploaded after each chunk.
+func (d *DBIO) insertRows(ctx context.Context, table string, rows [][]string, opts UploadOptions, progress func(int)) (*UploadResult, error) {
+	ret := new(UploadResult)
+	columns, header, err := d.uploadColumns(ctx, table, rows, opts)
+	if err != nil {
+		return ret, fmt.Errorf("formatting command for upload to %s: %w", table, err)
+	}
+	s, err := d.newInsertStatement(ctx, table, columns, opts)
+	if err != nil {
+		return ret, fmt.Errorf("formatting command for upload to %s: %w", table, err)
+	}
+	if err = checkWidths(table, rows[header:], s.width, header); err != nil {
+		return ret, err
+	}
+	rows = rows[header:]
+	var packet int
+	for ret.Rows < len(rows) {
+		start := ret.Rows
+		if err := ctx.Err(); err != nil {
+			return ret, fmt.Errorf("upload to %s cancelled after %d of %d rows: %w", table, start, len(rows), err)
+		}
+		if packet == 0 {
+			packet = d.maxPacket(ctx)
+		}
+		end := start + d.chunkSize(s, rows[start:], packet)
+		if err := d.insertSplit(ctx, s, rows[start:end], ret); err != nil {
+			return ret, err
+		}
+		if progress != nil {
+			progress(ret.Rows)
+		}
+	}
+	return ret, nil
+}
+
+// UploadCtx uploads rows to table using multi-row INSERT statements with bound arguments, handling rows which
+// duplicate existing keys according to opts.Mode. Rows are split into as many statements as needed to stay within MySQL's
+// placeholder limit and the server's max_allowed_packet (or DBIO.MaxPacket); a statement rejected as too large is split in half and
+// resubmitted. If opts.Null is not empty, values equal to it are inserted as NULL. The result contains the number of rows
+// inserted, updated, and ignored by each statement. If an error occurs, the rows before the failed statement have already been
+// uploaded unless the DBIO is a Tx. Rows hold a value for every column of table unless opts gives the columns (see UploadOptions);
+// a RowWidthError is returned without uploading any rows if a row has the wrong number of values.
+func (d *DBIO) UploadCtx(ctx context.Context, table string, rows [][]string, opts UploadOptions) (*UploadResult, error) {
+	return d.insertRows(ctx, table, rows, opts, nil)
+}
+
+// Upload uploads rows to table, handling rows which duplicate existing keys according to opts.Mode.
+func (d *DBIO) Upload(table string, rows [][]string, opts UploadOptions) (*UploadResult, error) {
+	return d.UploadCtx(context.Background(), table, rows, opts)
+}
+
+// InsertRowsCtx uploads rows to all columns of table using plain INSERT statements with bound arguments, so values are stored
+// exactly as given (see UploadCtx). Returns the number of rows uploaded.
+func (d *DBIO) InsertRowsCtx(ctx context.Context, table string, rows [][]string) (int, error) {
+	res, err := d.insertRows(ctx, table, rows, UploadOptions{}, nil)
+	return res.Rows, err
+}
+
+// InsertRows uploads rows to all columns of table using plain INSERT statements with bound arguments.
+func (d *DBIO) InsertRows(table string, rows [][]string) (int, error) {
+	return d.InsertRowsCtx(context.Background(), table, rows)
+}
@@ -0,0 +1,227 @@
+// Contains functions for loading rows with LOAD DATA LOCAL INFILE
+
+package dbIO
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"io"
+	"strings"
+	"sync/atomic"
+)
+
+// Counts readers registered with the driver so each has a unique name.
+var bulkReaders uint64
+
+// BulkOptions stores options for BulkLoad. The terminators and escape character describe the format of the input; they are
+// ignored by BulkLoadRows, which always writes rows in MySQL's default format.
+type BulkOptions struct {
+	// Columns lists the columns of the table in the order they appear in each row. All columns are loaded if it is empty.
+	Columns []string
+	// FieldTerminator separates values within a row. It defaults to a tab.
+	FieldTerminator string
+	// LineTerminator separates rows. It defaults to a newline.
+	LineTerminator string
+	// Enclosure is a character which may wrap values (e.g. a double quote for CSV files). Values are not enclosed if it is empty.
+	Enclosure string
+	// Escape is the escape character. It defaults to a backslash.
+	Escape string
+	// NoEscape disables escaping, so every character is read literally.
+	NoEscape bool
+	// IgnoreLines is the number of lines to skip at the start of the input (e.g. 1 for a header row).
+	IgnoreLines int
+	// Mode determines how rows which duplicate an existing key are handled. ModeInsert and ModeIgnore both skip duplicates
+	// (MySQL always ignores duplicates when loading local files), and ModeUpsert is not supported.
+	Mode InsertMode
+	// Null is the value which BulkLoadRows loads as NULL (e.g. NullNA). Every value is loaded unchanged if it is empty. Input to
+	// BulkLoad marks NULL values with \N.
+	Null string
+}
+
+// Warning stores a single row of the output of SHOW WARNINGS.
+type Warning struct {
+	Level   string
+	Code    int
+	Message string
+}
+
+// BulkResult stores the number of rows loaded and any warnings raised by the server (e.g. for truncated values or skipped
+// duplicates).
+type BulkResult struct {
+	// Rows is the number of affected rows reported by the server. In ModeReplace, each row which replaced an existing row is
+	// counted twice.
+	Rows     int64
+	Warnings []Warning
+}
+
+// Returns s as a quoted string literal.
+func quoteLiteral(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, "'", `\'`, "\x00", `\0`, "\n", `\n`, "\r", `\r`, "\t", `\t`, "\x1a", `\Z`)
+	return "'" + r.Replace(s) + "'"
+}
+
+// Returns the LOAD DATA statement for the reader registered under name.
+func (d *DBIO) bulkStatement(ctx context.Context, table, name string, opts BulkOptions) (string, error) {
+	tbl, err := d.checkTable(ctx, table)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("LOAD DATA LOCAL INFILE '%s' ", name))
+	switch opts.Mode {
+	case ModeInsert:
+	case ModeIgnore:
+		b.WriteString("IGNORE ")
+	case ModeReplace:
+		b.WriteString("REPLACE ")
+	default:
+		return "", fmt.Errorf("%w: insert mode %d is not supported by LOAD DATA", ErrInvalidQuery, opts.Mode)
+	}
+	b.WriteString("INTO TABLE " + tbl + " CHARACTER SET utf8mb4")
+	field, line, escape := opts.FieldTerminator, opts.LineTerminator, opts.Escape
+	if len(field) == 0 {
+		field = "\t"
+	}
+	if len(line) == 0 {
+		line = "\n"
+	}
+	if opts.NoEscape {
+		escape = ""
+	} else if len(escape) == 0 {
+		escape = `\`
+	}
+	if len(opts.Enclosure) > 1 || len(escape) > 1 {
+		return "", fmt.Errorf("%w: enclosure and escape must be single characters", ErrInvalidQuery)
+	}
+	b.WriteString(" FIELDS TERMINATED BY " + quoteLiteral(field))
+	if len(opts.Enclosure) > 0 {
+		b.WriteString(" OPTIONALLY ENCLOSED BY " + quoteLiteral(opts.Enclosure))
+	}
+	b.WriteString(" ESCAPED BY " + quoteLiteral(escape))
+	b.WriteString(" LINES TERMINATED BY " + quoteLiteral(line))
+	if opts.IgnoreLines > 0 {
+		b.WriteString(fmt.Sprintf(" IGNORE %d LINES", opts.IgnoreLines))
+	}
+	if len(opts.Columns) > 0 {
+		columns := make([]string, len(opts.Columns))
+		for i, c := range opts.Columns {
+			if columns[i], err = d.checkColumn(ctx, table, c); err != nil {
+				return "", err
+			}
+		}
+		b.WriteString(" (" + strings.Join(columns, ",") + ")")
+	}
+	return b.String() + ";", nil
+}
+
+// Reads the warnings from the last statement on conn.
+func readWarnings(ctx context.Context, conn querier) ([]Warning, error) {
+	rows, err := conn.QueryContext(ctx, "SHOW WARNINGS;")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ret []Warning
+	for rows.Next() {
+		var w Warning
+		if err = rows.Scan(&w.Level, &w.Code, &w.Message); err != nil {
+			return ret, err
+		}
+		ret = append(ret, w)
+	}
+	return ret, rows.Err()
+}
+
+// BulkLoadCtx loads delimited rows from r into table using LOAD DATA LOCAL INFILE, which is much faster than INSERT statements for
+// large imports. The input is streamed to the server, so it is not held in memory. The server must have local_infile enabled.
+// Warnings raised by the load are returned with the number of rows loaded. r is not closed, even if it implements io.Closer.
+func (d *DBIO) BulkLoadCtx(ctx context.Context, table string, r io.Reader, opts BulkOptions) (*BulkResult, error) {
+	name := fmt.Sprintf("dbio_bulk_%d", atomic.AddUint64(&bulkReaders, 1))
+	cmd, err := d.bulkStatement(ctx, table, "Reader::"+name, opts)
+	if err != nil {
+		return nil, fmt.Errorf("formatting bulk load for %s: %w", table, err)
+	}
+	// The driver closes readers which implement io.Closer, so only the Reader is passed on
+	mysql.RegisterReaderHandler(name, func() io.Reader { return struct{ io.Reader }{r} })
+	defer mysql.DeregisterReaderHandler(name)
+	conn := d.conn()
+	if d.tx == nil {
+		// SHOW WARNINGS must be run on the same connection as the load
+		c, err := d.DB.Conn(ctx)
+		if err != nil {
+			return nil, newError("loading", table, err)
+		}
+		defer c.Close()
+		conn = c
+	}
+	res, err := conn.ExecContext(ctx, cmd)
+	if err != nil {
+		return nil, newError("loading", table, err)
+	}
+	ret := new(BulkResult)
+	if ret.Rows, err = res.RowsAffected(); err != nil {
+		return ret, newError("loading", table, err)
+	}
+	if ret.Warnings, err = readWarnings(ctx, conn); err != nil {
+		return ret, fmt.Errorf("reading warnings for %s: %w", table, err)
+	}
+	return ret, nil
+}
+
+// BulkLoad loads delimited rows from r into table using LOAD DATA LOCAL INFILE.
+func (d *DBIO) BulkLoad(table string, r io.Reader, opts BulkOptions) (*BulkResult, error) {
+	return d.BulkLoadCtx(context.Background(), table, r, opts)
+}
+
+// Writes rows to w in MySQL's default LOAD DATA format. Values equal to null are written as NULL if null is not empty.
+func writeBulkRows(w io.Writer, rows [][]string, null string) error {
+	out := bufio.NewWriter(w)
+	r := strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`, "\x00", `\0`)
+	for _, row := range rows {
+		for i, v := range row {
+			if i > 0 {
+				out.WriteByte('\t')
+			}
+			if len(null) > 0 && v == null {
+				out.WriteString(`\N`)
+			} else if _, err := r.WriteString(out, v); err != nil {
+				return err
+			}
+		}
+		if err := out.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	return out.Flush()
+}
+
+// BulkLoadRowsCtx loads rows into table using LOAD DATA LOCAL INFILE. Values are escaped as needed, so they are stored exactly as
+// given; if opts.Null is not empty, values equal to it are loaded as NULL.
+func (d *DBIO) BulkLoadRowsCtx(ctx context.Context, table string, rows [][]string, opts BulkOptions) (*BulkResult, error) {
+	width := len(opts.Columns)
+	if width == 0 {
+		columns, err := d.tableColumns(ctx, table)
+		if err != nil {
+			return nil, fmt.Errorf("formatting bulk load for %s: %w", table, err)
+		}
+		width = len(columns)
+	}
+	if err := checkWidths(table, rows, width, 0); err != nil {
+		return nil, err
+	}
+	opts.FieldTerminator, opts.LineTerminator, opts.Enclosure, opts.Escape, opts.NoEscape, opts.IgnoreLines = "", "", "", "", false, 0
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeBulkRows(pw, rows, opts.Null))
+	}()
+	// Closing the reader stops the writer if the load fails before reading every row
+	defer pr.Close()
+	return d.BulkLoadCtx(ctx, table, pr, opts)
+}
+
+// BulkLoadRows loads rows into table using LOAD DATA LOCAL INFILE.
+func (d *DBIO) BulkLoadRows(table string, rows [][]string, opts BulkOptions) (*BulkResult, error) {
+	return d.BulkLoadRowsCtx(context.Background(), table, rows, opts)
+}
@@ -0,0 +1,251 @@
+// Contains functions for extracting single columns as typed slices
+
+package dbIO
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ConversionError is returned when values of a column cannot be converted to the requested type. The slice returned with it holds
+// the zero value at each failed row.
+type ConversionError struct {
+	Table  string
+	Column string
+	// Rows are the indices of the rows which could not be converted, in the order they were returned.
+	Rows []int
+	// Errs holds the error for each row in Rows.
+	Errs []error
+}
+
+// Error lists the failed rows and the first conversion error.
+func (e *ConversionError) Error() string {
+	rows := make([]string, 0, len(e.Rows))
+	for idx, i := range e.Rows {
+		if idx == 10 {
+			rows = append(rows, "...")
+			break
+		}
+		rows = append(rows, fmt.Sprint(i))
+	}
+	return fmt.Sprintf("converting %s from %s: %d rows failed (%s): %v", e.Column, e.Table, len(e.Rows), strings.Join(rows, ", "), e.Errs[0])
+}
+
+// Unwrap returns the error for each failed row.
+func (e *ConversionError) Unwrap() []error {
+	return e.Errs
+}
+
+// timeScanner reads DATE, DATETIME, and TIMESTAMP values whether or not the driver is configured with ParseTime.
+type timeScanner struct {
+	loc   *time.Location
+	time  time.Time
+	valid bool
+}
+
+// Scan reads a time returned by the driver.
+func (t *timeScanner) Scan(v interface{}) error {
+	var s string
+	switch x := v.(type) {
+	case nil:
+		t.valid = false
+		return nil
+	case time.Time:
+		t.time, t.valid = x, true
+		return nil
+	case []byte:
+		s = string(x)
+	case string:
+		s = x
+	default:
+		return fmt.Errorf("converting %T to time.Time is unsupported", v)
+	}
+	t.valid = true
+	if strings.HasPrefix(s, "0000-00-00") {
+		// MySQL's zero date
+		t.time = time.Time{}
+		return nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05.999999999", "2006-01-02", "15:04:05.999999999"} {
+		if ret, err := time.ParseInLocation(layout, s, t.loc); err == nil {
+			t.time = ret
+			return nil
+		}
+	}
+	return fmt.Errorf("parsing %q as time.Time: unrecognized format", s)
+}
+
+// Returns the scan destination for dest and a function which assigns the scanned value to dest. Times are parsed in Config.Loc,
+// or UTC if it is not set.
+func (d *DBIO) scanTarget(dest interface{}) (interface{}, func() error) {
+	t := &timeScanner{loc: time.UTC}
+	if d.Config != nil && d.Config.Config != nil && d.Config.Loc != nil {
+		t.loc = d.Config.Loc
+	}
+	switch x := dest.(type) {
+	case *time.Time:
+		return t, func() error {
+			if !t.valid {
+				return fmt.Errorf("converting NULL to time.Time is unsupported")
+			}
+			*x = t.time
+			return nil
+		}
+	case **time.Time:
+		return t, func() error {
+			if t.valid {
+				*x = &t.time
+			}
+			return nil
+		}
+	case *sql.NullTime:
+		return t, func() error {
+			*x = sql.NullTime{Time: t.time, Valid: t.valid}
+			return nil
+		}
+	}
+	return dest, func() error { return nil }
+}
+
+// Scans the current row into dest.
+func (d *DBIO) scanValues(rows *sql.Rows, dest ...interface{}) error {
+	targets := make([]interface{}, len(dest))
+	assign := make([]func() error, len(dest))
+	for i, v := range dest {
+		targets[i], assign[i] = d.scanTarget(v)
+	}
+	if err := rows.Scan(targets...); err != nil {
+		return err
+	}
+	for _, fn := range assign {
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetColumnCtx returns all values of column converted to T. T may be any type accepted by sql.Rows.Scan, including pointers and
+// sql.Null types for nullable columns; time.Time values are parsed even if Config.ParseTime is false. Rows which cannot be
+// converted are recorded in a ConversionError, which is returned after all rows have been read.
+func GetColumnCtx[T any](ctx context.Context, d *DBIO, table, column string) ([]T, error) {
+	rows, err := d.queryColumn(ctx, table, column)
+	if err != nil {
+		return nil, fmt.Errorf("extracting %s column from %s: %w", column, table, err)
+	}
+	defer rows.Close()
+	var ret []T
+	cerr := &ConversionError{Table: table, Column: column}
+	for idx := 0; rows.Next(); idx++ {
+		var val T
+		if err := d.scanValues(rows, &val); err != nil {
+			cerr.Rows = append(cerr.Rows, idx)
+			cerr.Errs = append(cerr.Errs, err)
+		}
+		ret = append(ret, val)
+	}
+	if err = rows.Err(); err != nil {
+		return ret, fmt.Errorf("reading %s from %s: %w", column, table, err)
+	} else if len(cerr.Rows) > 0 {
+		return ret, cerr
+	}
+	return ret, nil
+}
+
+// GetColumn returns all values of column converted to T.
+func GetColumn[T any](d *DBIO, table, column string) ([]T, error) {
+	return GetColumnCtx[T](context.Background(), d, table, column)
+}
+
+// GetColumnFloatCtx returns a slice of all entries in column of floats.
+func (d *DBIO) GetColumnFloatCtx(ctx context.Context, table, column string) ([]float64, error) {
+	return GetColumnCtx[float64](ctx, d, table, column)
+}
+
+// GetColumnFloat returns a slice of all entries in column of floats.
+func (d *DBIO) GetColumnFloat(table, column string) ([]float64, error) {
+	return d.GetColumnFloatCtx(context.Background(), table, column)
+}
+
+// GetColumnTimeCtx returns a slice of all entries in column of dates or times.
+func (d *DBIO) GetColumnTimeCtx(ctx context.Context, table, column string) ([]time.Time, error) {
+	return GetColumnCtx[time.Time](ctx, d, table, column)
+}
+
+// GetColumnTime returns a slice of all entries in column of dates or times.
+func (d *DBIO) GetColumnTime(table, column string) ([]time.Time, error) {
+	return d.GetColumnTimeCtx(context.Background(), table, column)
+}
+
+// GetColumnBoolCtx returns a slice of all entries in column of booleans (e.g. BOOL or TINYINT(1) columns).
+func (d *DBIO) GetColumnBoolCtx(ctx context.Context, table, column string) ([]bool, error) {
+	return GetColumnCtx[bool](ctx, d, table, column)
+}
+
+// GetColumnBool returns a slice of all entries in column of booleans.
+func (d *DBIO) GetColumnBool(table, column string) ([]bool, error) {
+	return d.GetColumnBoolCtx(context.Background(), table, column)
+}
+
+// GetColumnBytesCtx returns a slice of all entries in column as raw bytes (e.g. BLOB columns). NULL values are returned as nil.
+func (d *DBIO) GetColumnBytesCtx(ctx context.Context, table, column string) ([][]byte, error) {
+	return GetColumnCtx[[]byte](ctx, d, table, column)
+}
+
+// GetColumnBytes returns a slice of all entries in column as raw bytes.
+func (d *DBIO) GetColumnBytes(table, column string) ([][]byte, error) {
+	return d.GetColumnBytesCtx(context.Background(), table, column)
+}
+
+// GetColumnNullIntCtx returns a slice of all entries in column of nullable integers.
+func (d *DBIO) GetColumnNullIntCtx(ctx context.Context, table, column string) ([]sql.NullInt64, error) {
+	return GetColumnCtx[sql.NullInt64](ctx, d, table, column)
+}
+
+// GetColumnNullInt returns a slice of all entries in column of nullable integers.
+func (d *DBIO) GetColumnNullInt(table, column string) ([]sql.NullInt64, error) {
+	return d.GetColumnNullIntCtx(context.Background(), table, column)
+}
+
+// GetColumnNullFloatCtx returns a slice of all entries in column of nullable floats.
+func (d *DBIO) GetColumnNullFloatCtx(ctx context.Context, table, column string) ([]sql.NullFloat64, error) {
+	return GetColumnCtx[sql.NullFloat64](ctx, d, table, column)
+}
+
+// GetColumnNullFloat returns a slice of all entries in column of nullable floats.
+func (d *DBIO) GetColumnNullFloat(table, column string) ([]sql.NullFloat64, error) {
+	return d.GetColumnNullFloatCtx(context.Background(), table, column)
+}
+
+// GetColumnNullTextCtx returns a slice of all entries in column of nullable text.
+func (d *DBIO) GetColumnNullTextCtx(ctx context.Context, table, column string) ([]sql.NullString, error) {
+	return GetColumnCtx[sql.NullString](ctx, d, table, column)
+}
+
+// GetColumnNullText returns a slice of all entries in column of nullable text.
+func (d *DBIO) GetColumnNullText(table, column string) ([]sql.NullString, error) {
+	return d.GetColumnNullTextCtx(context.Background(), table, column)
+}
+
+// GetColumnNullTimeCtx returns a slice of all entries in column of nullable dates or times.
+func (d *DBIO) GetColumnNullTimeCtx(ctx context.Context, table, column string) ([]sql.NullTime, error) {
+	return GetColumnCtx[sql.NullTime](ctx, d, table, column)
+}
+
+// GetColumnNullTime returns a slice of all entries in column of nullable dates or times.
+func (d *DBIO) GetColumnNullTime(table, column string) ([]sql.NullTime, error) {
+	return d.GetColumnNullTimeCtx(context.Background(), table, column)
+}
+
+// GetColumnNullBoolCtx returns a slice of all entries in column of nullable booleans.
+func (d *DBIO) GetColumnNullBoolCtx(ctx context.Context, table, column string) ([]sql.NullBool, error) {
+	return GetColumnCtx[sql.NullBool](ctx, d, table, column)
+}
+
+// GetColumnNullBool returns a slice of all entries in column of nullable booleans.
+func (d *DBIO) GetColumnNullBool(table, column string) ([]sql.NullBool, error) {
+	return d.GetColumnNullBoolCtx(context.Background(), table, column)
+}
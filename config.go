@@ -0,0 +1,120 @@
+// Defines Config struct for connection options
+
+package dbIO
+
+import (
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultPort is the TCP port used when no port is given.
+const DefaultPort = 3306
+
+// Config stores connection options. It embeds the mysql driver's Config, so any driver option (e.g. ParseTime, Loc, Collation,
+// Timeout, ReadTimeout, WriteTimeout, or Params) may be set directly. Net and Addr are set from Host, Port, and Socket when
+// connecting. Use NewConfig to initialize the driver defaults.
+type Config struct {
+	*mysql.Config
+	// Host is the server's host name or IP address. It defaults to localhost.
+	Host string
+	// Port is the server's TCP port. It defaults to 3306.
+	Port int
+	// Socket is the path to a unix socket. Host and Port are ignored if it is given.
+	Socket string
+	// TLSMode determines whether TLS is used and how the server is verified. It defaults to TLSVerifyIdentity if TLSCA or
+	// TLSCert is given, and leaves the driver's TLS settings unchanged otherwise.
+	TLSMode TLSMode
+	// TLSCA is the path to a PEM-encoded CA bundle used to verify the server's certificate.
+	TLSCA string
+	// TLSCert is the path to a PEM-encoded client certificate.
+	TLSCert string
+	// TLSKey is the path to the PEM-encoded private key for TLSCert.
+	TLSKey string
+	// TLSServerName is the host name used to verify the server's certificate. It defaults to Host.
+	TLSServerName string
+	// Credentials supplies the user name and password (and optionally the server address) if they are not given.
+	Credentials CredentialProvider
+	// NonInteractive returns ErrCredentials when connecting instead of prompting for a missing user name or password.
+	NonInteractive bool
+	// MaxOpenConns is the maximum number of open connections. Zero leaves the pool unlimited.
+	MaxOpenConns int
+	// MaxIdleConns is the maximum number of idle connections. Zero uses the database/sql default and a negative value disables idle connections.
+	MaxIdleConns int
+	// ConnMaxLifetime is the maximum time a connection may be reused. Zero reuses connections indefinitely.
+	ConnMaxLifetime time.Duration
+	// ConnMaxIdleTime is the maximum time a connection may be idle. Zero keeps idle connections indefinitely.
+	ConnMaxIdleTime time.Duration
+}
+
+// NewConfig returns a Config with the driver's defaults and a utf8mb4 charset. Host may be given as host, host:port, or as the
+// path to a unix socket (any host beginning with a slash).
+func NewConfig(host, database, user, password string) *Config {
+	c := &Config{Config: mysql.NewConfig()}
+	// Params is used rather than Config.Apply, which requires driver version 1.9
+	c.Params = map[string]string{"charset": "utf8mb4"}
+	c.DBName = database
+	c.User = user
+	c.Passwd = password
+	c.setHost(host)
+	return c
+}
+
+// Parses host into host name and port or socket path.
+func (c *Config) setHost(host string) {
+	host = strings.TrimSpace(host)
+	if strings.HasPrefix(host, "/") {
+		c.Socket = host
+		return
+	}
+	if h, p, err := net.SplitHostPort(host); err == nil {
+		if port, err := strconv.Atoi(p); err == nil {
+			c.Host = h
+			c.Port = port
+			return
+		}
+	}
+	c.Host = strings.Trim(host, "[]")
+}
+
+// Returns the network and address of the server.
+func (c *Config) address() (string, string) {
+	if len(c.Socket) > 0 {
+		return "unix", c.Socket
+	}
+	host := c.Host
+	if len(host) < 1 {
+		host = "localhost"
+	}
+	port := c.Port
+	if port < 1 {
+		port = DefaultPort
+	}
+	return "tcp", net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// Returns a copy of the driver config with the network and address set.
+func (c *Config) driverConfig() *mysql.Config {
+	var ret *mysql.Config
+	if c.Config != nil {
+		ret = c.Config.Clone()
+	} else {
+		ret = mysql.NewConfig()
+	}
+	ret.Net, ret.Addr = c.address()
+	return ret
+}
+
+// FormatDSN returns the data source name for the connection.
+func (c *Config) FormatDSN() string {
+	return c.driverConfig().FormatDSN()
+}
+
+// String returns the formatted address of the server (e.g. tcp(localhost:3306)).
+func (c *Config) String() string {
+	n, a := c.address()
+	return fmt.Sprintf("%s(%s)", n, a)
+}
@@ -3,15 +3,12 @@
 package dbIO
 
 import (
-	"bufio"
+	"context"
 	"database/sql"
 	"fmt"
-	"github.com/Songmu/prompter"
-	// MySQL driver is required in sql package
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 	"log"
 	"os"
-	"strings"
 	"time"
 )
 
@@ -19,7 +16,7 @@ import (
 type DBIO struct {
 	// DB is the database connection. Any SQL query can be run directly using DB.
 	DB *sql.DB
-	// Host is the host IP.
+	// Host is the formatted server address (e.g. tcp(localhost:3306)).
 	Host string
 	// Database stores the name of the database.
 	Database string
@@ -31,121 +28,221 @@ type DBIO struct {
 	Starttime time.Time
 	// Columns stores a map with a comma-seperated string of column name for each table.
 	Columns map[string]string
-	logger  *log.Logger
+	// ForeignKeys stores the foreign key constraints in the database. It is populated by GetForeignKeys, or when a join is first
+	// made without giving the join columns.
+	ForeignKeys []ForeignKey
+	// Config stores the connection options. Database, User, and Password override the values in Config when connecting.
+	Config *Config
+	// NullValue is the string returned in place of NULL values by Execute and the other extraction methods. It is empty by
+	// default; NullNA and NullSentinel may be used to distinguish NULL from empty strings.
+	NullValue string
+	// Retry determines how inserts, updates, deletions, and queries which fail with transient errors are retried. Statements are
+	// not retried if it is nil.
+	Retry *RetryPolicy
+	// MaxPacket is the maximum size in bytes of statements used to upload rows. If it is zero, the server's max_allowed_packet is
+	// read once per connection.
+	MaxPacket int
+	logger    *log.Logger
+	tx        *sql.Tx
+	health    *healthState
+	packet    *packetCache
 }
 
-// NewDBIO returns an initialized struct. If host is left blank, it will default to localHost.
-func NewDBIO(host, database, user, password string) *DBIO {
+// NewDBIOConfig returns an initialized struct using the given connection options. A nil cfg connects to localhost with the
+// default options.
+func NewDBIOConfig(cfg *Config) *DBIO {
 	d := new(DBIO)
-	host = strings.TrimSpace(host)
-	if len(host) < 1 {
-		d.Host = "localhost"
-	} else {
-		d.Host = fmt.Sprintf("tcp(%s:3306)", host)
+	if cfg == nil {
+		cfg = NewConfig("", "", "", "")
+	} else if cfg.Config == nil {
+		cfg.Config = mysql.NewConfig()
 	}
-	d.Database = database
-	d.User = user
-	d.Password = password
+	d.Config = cfg
+	d.Host = cfg.String()
+	d.Database = cfg.DBName
+	d.User = cfg.User
+	d.Password = cfg.Passwd
 	d.logger = log.New(os.Stderr, "dbIO_Log: ", log.Ldate|log.Ltime)
+	d.packet = new(packetCache)
+	d.health = new(healthState)
 	return d
 }
 
+// NewDBIO returns an initialized struct. If host is left blank, it will default to localhost. Host may include a port
+// (host:port) or be the path to a unix socket.
+func NewDBIO(host, database, user, password string) *DBIO {
+	return NewDBIOConfig(NewConfig(host, database, user, password))
+}
+
 // Creates new database with utf8 charset
-func (d *DBIO) create(database string) {
-	cmd, err := d.DB.Prepare(fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s CHARACTER SET utf8mb4;", database))
+func (d *DBIO) create(ctx context.Context, database string) error {
+	cmd, err := d.DB.PrepareContext(ctx, fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s CHARACTER SET utf8mb4;", quoteIdentifier(database)))
+	if err != nil {
+		return fmt.Errorf("formatting command to create database %s: %w", database, err)
+	}
+	defer cmd.Close()
+	if _, err = cmd.ExecContext(ctx); err != nil {
+		return fmt.Errorf("creating database %s: %w", database, err)
+	}
+	return nil
+}
+
+// Closes the current connection and reconnects to the given database.
+func (d *DBIO) reconnect(ctx context.Context, database string) error {
+	d.DB.Close()
+	d.Database = database
+	if err := d.connect(ctx); err != nil {
+		return err
+	}
+	if err := d.DB.PingContext(ctx); err != nil {
+		return fmt.Errorf("%w: %w", ErrConnection, err)
+	}
+	return nil
+}
+
+// CreateDatabaseCtx connects to MySQL and creates a new database. It returns a connection to the new database.
+func CreateDatabaseCtx(ctx context.Context, host, database, user string) (*DBIO, error) {
+	d, err := ConnectCtx(ctx, host, "", user, "")
 	if err != nil {
-		d.logger.Printf("[Error] Formatting command to create database %s: %v\n", database, err)
-	} else {
-		_, err = cmd.Exec()
-		if err != nil {
-			d.logger.Printf("[Error] Creating database %s: %v\n", database, err)
-		}
+		return d, err
+	}
+	if err = d.create(ctx, database); err != nil {
+		return d, err
 	}
+	// Return conneciton to given database
+	return d, d.reconnect(ctx, database)
+}
+
+// CreateDatabaseE connects to MySQL and creates a new database. It returns a connection to the new database.
+func CreateDatabaseE(host, database, user string) (*DBIO, error) {
+	return CreateDatabaseCtx(context.Background(), host, database, user)
 }
 
 // CreateDatabase connects to MySQL and creates a new database.
+//
+// Deprecated: Use CreateDatabaseE, which returns errors instead of exiting.
 func CreateDatabase(host, database, user string) *DBIO {
-	d, err := Connect(host, "", user, "")
+	d, err := CreateDatabaseE(host, database, user)
 	if err != nil {
 		d.logger.Fatalln(err)
 	}
-	d.create(database)
-	// Return conneciton to given database
-	d.Database = database
-	d.connect()
 	return d
 }
 
-// ReplaceDatabase deletes the given database and creates a new, empty, one (for testing).
-func ReplaceDatabase(host, database, user, password string) *DBIO {
-	d, err := Connect(host, "", user, password)
+// ReplaceDatabaseCtx deletes the given database and creates a new, empty, one (for testing). It returns a connection to the new database.
+func ReplaceDatabaseCtx(ctx context.Context, host, database, user, password string) (*DBIO, error) {
+	d, err := ConnectCtx(ctx, host, "", user, password)
 	if err != nil {
-		d.logger.Fatalln(err)
+		return d, err
 	}
-	cmd, err := d.DB.Prepare(fmt.Sprintf("DROP DATABASE IF EXISTS %s;", database))
+	cmd, err := d.DB.PrepareContext(ctx, fmt.Sprintf("DROP DATABASE IF EXISTS %s;", quoteIdentifier(database)))
 	if err != nil {
-		d.logger.Printf("[Error] Formatting command to delete database %s: %v\n", database, err)
-	} else {
-		_, err = cmd.Exec()
-		if err != nil {
-			d.logger.Printf("[Error] Deleting database %s: %v\n", database, err)
-		} else {
-			d.create(database)
-		}
+		return d, fmt.Errorf("formatting command to delete database %s: %w", database, err)
+	}
+	_, err = cmd.ExecContext(ctx)
+	cmd.Close()
+	if err != nil {
+		return d, fmt.Errorf("deleting database %s: %w", database, err)
+	}
+	if err = d.create(ctx, database); err != nil {
+		return d, err
 	}
 	// Return conneciton to given database
-	d.Database = database
-	d.connect()
+	return d, d.reconnect(ctx, database)
+}
+
+// ReplaceDatabaseE deletes the given database and creates a new, empty, one (for testing). It returns a connection to the new database.
+func ReplaceDatabaseE(host, database, user, password string) (*DBIO, error) {
+	return ReplaceDatabaseCtx(context.Background(), host, database, user, password)
+}
+
+// ReplaceDatabase deletes the given database and creates a new, empty, one (for testing).
+//
+// Deprecated: Use ReplaceDatabaseE, which returns errors instead of exiting.
+func ReplaceDatabase(host, database, user, password string) *DBIO {
+	d, err := ReplaceDatabaseE(host, database, user, password)
+	if err != nil {
+		d.logger.Fatalln(err)
+	}
 	return d
 }
 
 // Connects to database
-func (d *DBIO) connect() error {
-	var err error
-	if d.User == "" {
-		reader := bufio.NewReader(os.Stdin)
-		fmt.Print("\n\tEnter MySQL user name: ")
-		text, _ := reader.ReadString('\n')
-		d.User = strings.TrimSpace(text)
-	}
-	if d.User != "guest" && d.Password == "" {
-		// Prompt for password
-		d.Password = prompter.Password("\n\tEnter MySQL password")
+func (d *DBIO) connect(ctx context.Context) error {
+	if err := d.resolveCredentials(ctx); err != nil {
+		return err
 	}
 	// Begin recording time after password input
 	d.Starttime = time.Now()
-	cmd := d.User + ":" + d.Password + "@" + d.Host + "/"
-	if len(d.Database) > 0 {
-		// Connect to specific database
-		cmd = cmd + d.Database + "?charset=utf8mb4"
+	cfg := d.Config.driverConfig()
+	cfg.User = d.User
+	cfg.Passwd = d.Password
+	cfg.DBName = d.Database
+	if err := d.Config.registerTLS(cfg); err != nil {
+		return err
+	}
+	conn, err := mysql.NewConnector(cfg)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrConnection, err)
 	}
-	d.DB, err = sql.Open("mysql", cmd)
-	return err
+	d.DB = sql.OpenDB(conn)
+	d.Config.applyPool(d.DB)
+	d.packet = new(packetCache)
+	return nil
+}
+
+// ConnectCtx attempts to connect to the MySQL database located at host/database using the given user name and password.
+// Returned errors wrap ErrConnection.
+func ConnectCtx(ctx context.Context, host, database, user, password string) (*DBIO, error) {
+	return NewDBIO(host, database, user, password).open(ctx)
+}
+
+// Connects d and pings the server.
+func (d *DBIO) open(ctx context.Context) (*DBIO, error) {
+	if err := d.connect(ctx); err != nil {
+		return d, err
+	}
+	if err := d.DB.PingContext(ctx); err != nil {
+		return d, fmt.Errorf("%w: cannot connect to database: %w", ErrConnection, err)
+	}
+	return d, nil
+}
+
+// ConnectConfigCtx attempts to connect to the MySQL database using the given connection options. Returned errors wrap ErrConnection.
+func ConnectConfigCtx(ctx context.Context, cfg *Config) (*DBIO, error) {
+	return NewDBIOConfig(cfg).open(ctx)
+}
+
+// ConnectConfig attempts to connect to the MySQL database using the given connection options. Returned errors wrap ErrConnection.
+func ConnectConfig(cfg *Config) (*DBIO, error) {
+	return ConnectConfigCtx(context.Background(), cfg)
 }
 
 // Connect attempts to connect to the MySQL database located at host/database using the given user name and password.
+// Returned errors wrap ErrConnection.
 func Connect(host, database, user, password string) (*DBIO, error) {
+	return ConnectCtx(context.Background(), host, database, user, password)
+}
+
+// PingCtx returns nil if the given credentials are valid, and discards the connection.
+func PingCtx(ctx context.Context, host, database, user, password string) error {
 	d := NewDBIO(host, database, user, password)
-	err := d.connect()
-	if err != nil {
-		err = fmt.Errorf("\n\t[Error] Incorrect username or password: %v", err)
+	if err := d.connect(ctx); err != nil {
+		return err
 	}
-	if err = d.DB.Ping(); err != nil {
-		err = fmt.Errorf("\n\t[Error] Cannot connect to database: %v", err)
+	defer d.DB.Close()
+	if err := d.DB.PingContext(ctx); err != nil {
+		return fmt.Errorf("%w: %w", ErrConnection, err)
 	}
-	return d, err
+	return nil
+}
+
+// PingE returns nil if the given credentials are valid, and discards the connection.
+func PingE(host, database, user, password string) error {
+	return PingCtx(context.Background(), host, database, user, password)
 }
 
 // Ping returns true if the given credentials are valid, and discards the connection.
 func Ping(host, database, user, password string) bool {
-	ret := false
-	d := NewDBIO(host, database, user, password)
-	err := d.connect()
-	if err == nil {
-		if err = d.DB.Ping(); err == nil {
-			// Return true if no errors are encountered
-			ret = true
-		}
-	}
-	return ret
+	return PingE(host, database, user, password) == nil
 }
@@ -0,0 +1,273 @@
+// Defines credential providers for non-interactive connections
+
+package dbIO
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Songmu/prompter"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Credentials stores the user name, password, and server address supplied by a CredentialProvider. Empty fields are unknown.
+type Credentials struct {
+	User     string
+	Password string
+	Host     string
+	Port     int
+	Socket   string
+}
+
+// Sets any empty fields in c to the values in v.
+func (c *Credentials) fill(v Credentials) {
+	if len(c.User) < 1 {
+		c.User = v.User
+	}
+	if len(c.Password) < 1 {
+		c.Password = v.Password
+	}
+	if len(c.Host) < 1 {
+		c.Host = v.Host
+	}
+	if c.Port < 1 {
+		c.Port = v.Port
+	}
+	if len(c.Socket) < 1 {
+		c.Socket = v.Socket
+	}
+}
+
+// Returns true if a password prompt would be needed.
+func (c Credentials) missing() bool {
+	return len(c.User) < 1 || (c.User != "guest" && len(c.Password) < 1)
+}
+
+// CredentialProvider supplies connection credentials. Credentials is given the values which are already known and returns them
+// with any missing values it can supply filled in. Providers never replace values which are already set.
+type CredentialProvider interface {
+	Credentials(ctx context.Context, current Credentials) (Credentials, error)
+}
+
+// ChainProvider calls each provider in order until no values are missing.
+type ChainProvider []CredentialProvider
+
+// Credentials calls each provider in order until the user name and password are known.
+func (p ChainProvider) Credentials(ctx context.Context, current Credentials) (Credentials, error) {
+	var err error
+	for _, i := range p {
+		if !current.missing() {
+			break
+		}
+		if current, err = i.Credentials(ctx, current); err != nil {
+			return current, err
+		}
+	}
+	return current, nil
+}
+
+// EnvProvider reads credentials from environment variables. Each variable defaults to the name used by the mysql client:
+// MYSQL_USER, MYSQL_PWD, MYSQL_HOST, MYSQL_TCP_PORT, and MYSQL_UNIX_PORT.
+type EnvProvider struct {
+	UserVar     string
+	PasswordVar string
+	HostVar     string
+	PortVar     string
+	SocketVar   string
+}
+
+// Returns the value of the environment variable name, or def if name is empty.
+func getenv(name, def string) string {
+	if len(name) < 1 {
+		name = def
+	}
+	return os.Getenv(name)
+}
+
+// Credentials fills in missing values from the environment.
+func (p EnvProvider) Credentials(ctx context.Context, current Credentials) (Credentials, error) {
+	v := Credentials{
+		User:     getenv(p.UserVar, "MYSQL_USER"),
+		Password: getenv(p.PasswordVar, "MYSQL_PWD"),
+		Host:     getenv(p.HostVar, "MYSQL_HOST"),
+		Socket:   getenv(p.SocketVar, "MYSQL_UNIX_PORT"),
+	}
+	if port := getenv(p.PortVar, "MYSQL_TCP_PORT"); len(port) > 0 {
+		n, err := strconv.Atoi(port)
+		if err != nil {
+			return current, fmt.Errorf("%w: invalid port %s in environment: %w", ErrCredentials, port, err)
+		}
+		v.Port = n
+	}
+	current.fill(v)
+	return current, nil
+}
+
+// OptionFileProvider reads credentials from a MySQL option file (e.g. ~/.my.cnf). The user, password, host, port, and socket
+// options are read from the given groups.
+type OptionFileProvider struct {
+	// Path is the option file to read. It defaults to ~/.my.cnf, which is skipped if it does not exist.
+	Path string
+	// Groups are the option groups to read. It defaults to the client group.
+	Groups []string
+}
+
+// Returns the value of an option with any quotes or trailing comment removed.
+func optionValue(v string) string {
+	v = strings.TrimSpace(v)
+	if len(v) > 1 && (v[0] == '"' || v[0] == '\'') {
+		if idx := strings.IndexByte(v[1:], v[0]); idx >= 0 {
+			return v[1 : idx+1]
+		}
+	}
+	if idx := strings.Index(v, "#"); idx >= 0 {
+		v = strings.TrimSpace(v[:idx])
+	}
+	return v
+}
+
+// Returns the credentials in the given groups of the option file.
+func readOptionFile(r io.Reader, groups []string) (Credentials, error) {
+	var ret Credentials
+	read := false
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) < 1 || line[0] == '#' || line[0] == ';' || line[0] == '!' {
+			continue
+		} else if line[0] == '[' {
+			group := strings.TrimSpace(strings.Trim(line, "[]"))
+			read = false
+			for _, i := range groups {
+				if strings.EqualFold(group, i) {
+					read = true
+				}
+			}
+		} else if read {
+			k, v, _ := strings.Cut(line, "=")
+			switch strings.ReplaceAll(strings.ToLower(strings.TrimSpace(k)), "-", "_") {
+			case "user":
+				ret.User = optionValue(v)
+			case "password":
+				ret.Password = optionValue(v)
+			case "host":
+				ret.Host = optionValue(v)
+			case "socket":
+				ret.Socket = optionValue(v)
+			case "port":
+				n, err := strconv.Atoi(optionValue(v))
+				if err != nil {
+					return ret, fmt.Errorf("invalid port %s: %w", optionValue(v), err)
+				}
+				ret.Port = n
+			}
+		}
+	}
+	return ret, scanner.Err()
+}
+
+// Credentials fills in missing values from the option file.
+func (p OptionFileProvider) Credentials(ctx context.Context, current Credentials) (Credentials, error) {
+	path := p.Path
+	if len(path) < 1 {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return current, nil
+		}
+		path = filepath.Join(home, ".my.cnf")
+	}
+	groups := p.Groups
+	if len(groups) < 1 {
+		groups = []string{"client"}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		if len(p.Path) < 1 && errors.Is(err, fs.ErrNotExist) {
+			return current, nil
+		}
+		return current, fmt.Errorf("%w: %w", ErrCredentials, err)
+	}
+	defer f.Close()
+	v, err := readOptionFile(f, groups)
+	if err != nil {
+		return current, fmt.Errorf("%w: reading %s: %w", ErrCredentials, path, err)
+	}
+	current.fill(v)
+	return current, nil
+}
+
+// PasswordFileProvider reads the password from the first line of a file (e.g. a mounted secret).
+type PasswordFileProvider struct {
+	Path string
+}
+
+// Credentials fills in the password from the file if it is missing.
+func (p PasswordFileProvider) Credentials(ctx context.Context, current Credentials) (Credentials, error) {
+	if len(current.Password) > 0 {
+		return current, nil
+	}
+	b, err := os.ReadFile(p.Path)
+	if err != nil {
+		return current, fmt.Errorf("%w: %w", ErrCredentials, err)
+	}
+	line, _, _ := strings.Cut(string(b), "\n")
+	current.Password = strings.TrimRight(line, "\r")
+	return current, nil
+}
+
+// PromptProvider prompts for the user name and password on the terminal. The password is not requested for the guest user.
+type PromptProvider struct{}
+
+// Credentials prompts for the user name and password if they are missing.
+func (p PromptProvider) Credentials(ctx context.Context, current Credentials) (Credentials, error) {
+	if current.User == "" {
+		reader := bufio.NewReader(os.Stdin)
+		fmt.Print("\n\tEnter MySQL user name: ")
+		text, _ := reader.ReadString('\n')
+		current.User = strings.TrimSpace(text)
+	}
+	if current.User != "guest" && current.Password == "" {
+		current.Password = prompter.Password("\n\tEnter MySQL password")
+	}
+	return current, nil
+}
+
+// Resolves missing credentials from Config.Credentials, and then by prompting unless Config.NonInteractive is set.
+func (d *DBIO) resolveCredentials(ctx context.Context) error {
+	c := d.Config
+	current := Credentials{User: d.User, Password: d.Password}
+	if !current.missing() {
+		return nil
+	}
+	var providers ChainProvider
+	if c.Credentials != nil {
+		providers = append(providers, c.Credentials)
+	}
+	if !c.NonInteractive {
+		providers = append(providers, PromptProvider{})
+	}
+	current, err := providers.Credentials(ctx, current)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrConnection, err)
+	}
+	if current.missing() {
+		return fmt.Errorf("%w: %w: no user name or password for %s", ErrConnection, ErrCredentials, d.Host)
+	}
+	d.User = current.User
+	d.Password = current.Password
+	if len(c.Host) < 1 && len(c.Socket) < 1 {
+		c.Host = current.Host
+		c.Socket = current.Socket
+	}
+	if c.Port < 1 {
+		c.Port = current.Port
+	}
+	d.Host = c.String()
+	return nil
+}
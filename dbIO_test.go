@@ -3,8 +3,26 @@
 package dbIO
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestEscapeChars(t *testing.T) {
@@ -84,21 +102,1500 @@ func TestFormatSlice(t *testing.T) {
 	}
 }
 
-func TestAddApostrophes(t *testing.T) {
-	// Tests addApostrophes function (in extract.go)
+func getTestDBIO() *DBIO {
+	// Returns DBIO struct with columns for testing query formatting
+	d := NewDBIO("", "test", "guest", "")
+	d.Columns = map[string]string{
+		"Accounts": "account_id,Account,submitter_name",
+		"Patient":  "ID,Sex,Age,Species",
+	}
+	return d
+}
+
+func TestSplitKeys(t *testing.T) {
+	// Tests splitKeys function (in query.go)
 	matches := []struct {
 		input    string
+		expected []string
+	}{
+		{"1,Weasel,15", []string{"1", "Weasel", "15"}},
+		{"'2','stoat','9'", []string{"2", "stoat", "9"}},
+		{"3, egret ,NA", []string{"3", "egret", "NA"}},
+		{`4,black\_footed\_ferret,20`, []string{"4", `black\_footed\_ferret`, "20"}},
+	}
+	for _, i := range matches {
+		actual := splitKeys(i.input)
+		if len(actual) != len(i.expected) {
+			t.Errorf("Actual number of keys %d is not equal to expected: %d", len(actual), len(i.expected))
+			continue
+		}
+		for idx, j := range actual {
+			if j != i.expected[idx] {
+				t.Errorf("Actual key %v is not equal to expected: %s", j, i.expected[idx])
+			}
+		}
+	}
+}
+
+func TestCheckIdentifiers(t *testing.T) {
+	// Tests table, column, and operator validation (in query.go)
+	d := getTestDBIO()
+	valid := []struct {
+		table    string
+		target   string
 		expected string
 	}{
-		{"1,Weasel,15", "'1','Weasel','15'"},
-		{"2,stoat,9", "'2','stoat','9'"},
-		{"3,egret,NA", "'3','egret','NA'"},
-		{`4,black\_footed\_ferret,20`, `'4','black\_footed\_ferret','20'`},
+		{"Accounts", "*", "*"},
+		{"Accounts", "Account", "`Account`"},
+		{"Accounts", "account_id, ACCOUNT", "`account_id`,`Account`"},
+		{"Patient", "Species", "`Species`"},
+	}
+	for _, i := range valid {
+		actual, err := d.checkTargets(context.Background(), i.table, i.target)
+		if err != nil {
+			t.Errorf("Unexpected error validating %s.%s: %v", i.table, i.target, err)
+		} else if actual != i.expected {
+			t.Errorf("Actual target %s is not equal to expected: %s", actual, i.expected)
+		}
+	}
+	invalid := []struct {
+		table  string
+		target string
+	}{
+		{"Accounts; DROP TABLE Patient", "*"},
+		{"Missing", "ID"},
+		{"Patient", "Age FROM Patient; --"},
+		{"Patient", "account_id"},
+		{"Patient", "`ID`"},
+	}
+	for _, i := range invalid {
+		if _, err := d.checkTargets(context.Background(), i.table, i.target); err == nil {
+			t.Errorf("Invalid identifier %s.%s did not return an error.", i.table, i.target)
+		}
+	}
+	for _, i := range []string{"OR 1=1", ";", "= '' OR", "IN"} {
+		if _, err := checkOperator(i); err == nil {
+			t.Errorf("Invalid operator %s did not return an error.", i)
+		}
+	}
+	if actual, err := checkOperator("not  like"); err != nil || actual != "NOT LIKE" {
+		t.Errorf("Actual operator %s is not equal to expected: NOT LIKE", actual)
+	}
+	if actual := quoteIdentifier("a`b"); actual != "`a``b`" {
+		t.Errorf("Actual quoted identifier %s is not equal to expected: `a``b`", actual)
+	}
+}
+
+type testQuery struct {
+	name string
+	cmd  string
+	args []interface{}
+	err  error
+}
+
+func TestHostileKeys(t *testing.T) {
+	// Tests that keys are passed as arguments rather than formatted into queries (in query.go)
+	d := getTestDBIO()
+	hostile := []string{
+		"x' OR '1'='1",
+		"'; DROP TABLE Accounts; --",
+		`\' OR 1=1 #`,
+	}
+	for _, key := range hostile {
+		var queries []testQuery
+		cmd, args, err := d.getRowsQuery(context.Background(), "Accounts", "Account", key, "*")
+		queries = append(queries, testQuery{"GetRows", cmd, args, err})
+		cmd, args, err = d.evaluateQuery(context.Background(), "Accounts", "Account", ">=", key, "account_id")
+		queries = append(queries, testQuery{"EvaluateRows", cmd, args, err})
+		cmd, args, err = d.containsQuery(context.Background(), "Accounts", "Account", key, "account_id,Account")
+		queries = append(queries, testQuery{"ColumnContains", cmd, args, err})
+		cmd, args, err = d.countQuery(context.Background(), "Accounts", "Account", "*", "=", key, false)
+		queries = append(queries, testQuery{"Count", cmd, args, err})
+		for _, q := range queries {
+			if q.err != nil {
+				t.Errorf("Unexpected error formatting %s query: %v", q.name, q.err)
+			} else if strings.Contains(q.cmd, key) || strings.Contains(q.cmd, "'") {
+				t.Errorf("%s query %s contains literal key.", q.name, q.cmd)
+			} else if len(q.args) != 1 || q.args[0] != key {
+				t.Errorf("Actual %s arguments %v are not equal to expected: [%s]", q.name, q.args, key)
+			} else if strings.Count(q.cmd, "?") != 1 {
+				t.Errorf("%s query %s does not contain a single placeholder.", q.name, q.cmd)
+			}
+		}
+	}
+	for _, i := range []string{"*", ""} {
+		if _, _, err := d.countQuery(context.Background(), "Accounts", "", i, "", "", true); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("Actual error %v counting distinct %q is not equal to expected: %v", err, i, ErrInvalidQuery)
+		}
+	}
+	cmd, args, err := d.countQuery(context.Background(), "Accounts", "", "Account", "", "", true)
+	if expected := "SELECT COUNT(DISTINCT `Account`) FROM `Accounts`;"; err != nil || cmd != expected {
+		t.Errorf("Actual distinct count query %s is not equal to expected: %s (%v)", cmd, expected, err)
+	}
+	cmd, args, err = d.getRowsQuery(context.Background(), "Accounts", "Account", "a,x' OR '1'='1,c", "*")
+	expected := "SELECT * FROM `Accounts` WHERE `Account` IN (?,?,?);"
+	if err != nil {
+		t.Errorf("Unexpected error formatting list query: %v", err)
+	} else if cmd != expected {
+		t.Errorf("Actual list query %s is not equal to expected: %s", cmd, expected)
+	} else if len(args) != 3 || args[1] != "x' OR '1'='1" {
+		t.Errorf("Actual list arguments %v are incorrect.", args)
+	}
+}
+
+func TestSentinelErrors(t *testing.T) {
+	// Tests that validation and schema errors wrap sentinel errors (in errors.go)
+	d := getTestDBIO()
+	if _, err := d.GetRowsE("Missing", "ID", "1", "*"); !errors.Is(err, ErrUnknownTable) {
+		t.Errorf("Actual error %v does not wrap ErrUnknownTable.", err)
+	}
+	if _, err := d.GetRowsE("Patient", "Missing", "1", "*"); !errors.Is(err, ErrUnknownColumn) {
+		t.Errorf("Actual error %v does not wrap ErrUnknownColumn.", err)
+	}
+	if _, err := d.EvaluateRowsE("Patient", "Age", "OR", "1", "*"); !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("Actual error %v does not wrap ErrInvalidQuery.", err)
+	}
+	infile := filepath.Join(t.TempDir(), "schema.txt")
+	schema := "CREATE TABLE IF NOT EXISTS Accounts (\n\taccount_id INT PRIMARY KEY,\n\tAccount TEXT\n);\n\nCREATE TABLE IF NOT EXISTS Patient (\n\tID INT\n"
+	if err := os.WriteFile(infile, []byte(schema), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tables, err := d.ReadColumnsE(infile)
+	if !errors.Is(err, ErrSchemaParse) {
+		t.Errorf("Actual error %v does not wrap ErrSchemaParse.", err)
+	}
+	if len(tables) != 1 {
+		t.Errorf("Actual number of statements %d is not equal to expected: 1", len(tables))
+	}
+	// The deprecated wrapper ignores the unterminated statement
+	if tables = d.ReadColumns(infile); len(tables) != 1 {
+		t.Errorf("Actual number of statements %d from ReadColumns is not equal to expected: 1", len(tables))
+	}
+}
+
+func TestClassifyErrors(t *testing.T) {
+	// Tests classification of driver errors (in errors.go)
+	matches := []struct {
+		err      error
+		expected ErrorKind
+		retry    bool
+	}{
+		{&mysql.MySQLError{Number: 1062, Message: "Duplicate entry '1' for key 'PRIMARY'"}, KindDuplicateKey, false},
+		{&mysql.MySQLError{Number: 1452}, KindForeignKey, false},
+		{&mysql.MySQLError{Number: 1205}, KindLockWaitTimeout, true},
+		{&mysql.MySQLError{Number: 1213}, KindDeadlock, true},
+		{&mysql.MySQLError{Number: 1153}, KindPacketTooLarge, false},
+		{mysql.ErrPktTooLarge, KindPacketTooLarge, false},
+		{&mysql.MySQLError{Number: 1054}, KindUnknownColumn, false},
+		{&mysql.MySQLError{Number: 1045}, KindAccessDenied, false},
+		{mysql.ErrInvalidConn, KindBadConnection, true},
+		{driver.ErrBadConn, KindBadConnection, true},
+		{&mysql.MySQLError{Number: 1064}, KindOther, false},
+		{errors.New("other"), KindOther, false},
 	}
 	for _, i := range matches {
-		actual := addApostrophes(i.input)
-		if actual != i.expected {
-			t.Errorf("Actual apostrophe string %s is not equal to expected: %s", actual, i.expected)
+		err := newError("uploading to", "Accounts", i.err)
+		if actual := Kind(err); actual != i.expected {
+			t.Errorf("Actual kind of %v %s is not equal to expected: %s", i.err, actual, i.expected)
+		}
+		if actual := IsRetryable(fmt.Errorf("wrapped: %w", err)); actual != i.retry {
+			t.Errorf("Actual retryable value of %v %v is not equal to expected: %v", i.err, actual, i.retry)
+		}
+		if !errors.Is(err, i.err) {
+			t.Errorf("Error %v does not wrap %v.", err, i.err)
+		}
+	}
+	err := newError("uploading to", "Accounts", &mysql.MySQLError{Number: 1062})
+	if !IsDuplicateKey(err) || IsDeadlock(err) {
+		t.Errorf("Error %v was not classified as a duplicate key.", err)
+	}
+	var e *Error
+	if !errors.As(err, &e) || e.Number() != 1062 || e.Table != "Accounts" {
+		t.Errorf("Error %v does not report table and error number.", err)
+	}
+	if IsRetryable(nil) || IsDuplicateKey(nil) {
+		t.Error("Nil error was classified as a failure.")
+	}
+}
+
+func compareStatements(t *testing.T, actual, expected []string) {
+	// Compares recorded statement prefixes to expected
+	if len(actual) != len(expected) {
+		t.Errorf("Actual statements %v are not equal to expected: %v", actual, expected)
+		return
+	}
+	for idx, i := range actual {
+		if !strings.HasPrefix(i, expected[idx]) {
+			t.Errorf("Actual statement %s does not begin with expected: %s", i, expected[idx])
+		}
+	}
+}
+
+func TestWithTx(t *testing.T) {
+	// Tests commit, rollback, and savepoints (in tx.go)
+	d, rec := newFakeDBIO(t)
+	err := d.WithTx(func(tx *Tx) error {
+		if err := tx.DeleteRowE("Accounts", "account_id", "1"); err != nil {
+			return err
+		}
+		return tx.UpdateRowE("Accounts", "Account", "a", "account_id", "=", "2")
+	})
+	if err != nil {
+		t.Errorf("Unexpected error from transaction: %v", err)
+	}
+	compareStatements(t, rec.log(), []string{"BEGIN", "DELETE", "UPDATE", "COMMIT"})
+
+	d, rec = newFakeDBIO(t)
+	expected := errors.New("failed")
+	err = d.WithTx(func(tx *Tx) error {
+		tx.DeleteRowE("Accounts", "account_id", "1")
+		return expected
+	})
+	if !errors.Is(err, expected) {
+		t.Errorf("Actual transaction error %v is not equal to expected: %v", err, expected)
+	}
+	compareStatements(t, rec.log(), []string{"BEGIN", "DELETE", "ROLLBACK"})
+
+	d, rec = newFakeDBIO(t)
+	func() {
+		defer func() {
+			if p := recover(); p == nil {
+				t.Error("Panic was not propagated from transaction.")
+			}
+		}()
+		d.WithTx(func(tx *Tx) error {
+			tx.DeleteRowE("Accounts", "account_id", "1")
+			panic("failed")
+		})
+	}()
+	compareStatements(t, rec.log(), []string{"BEGIN", "DELETE", "ROLLBACK"})
+
+	d, rec = newFakeDBIO(t)
+	err = d.WithTx(func(tx *Tx) error {
+		tx.DeleteRowE("Accounts", "account_id", "1")
+		if err := tx.WithTx(func(n *Tx) error {
+			n.DeleteRowE("Accounts", "account_id", "2")
+			return expected
+		}); !errors.Is(err, expected) {
+			t.Errorf("Actual savepoint error %v is not equal to expected: %v", err, expected)
+		}
+		return tx.WithTx(func(n *Tx) error {
+			return n.DeleteRowE("Accounts", "account_id", "3")
+		})
+	})
+	if err != nil {
+		t.Errorf("Unexpected error from transaction: %v", err)
+	}
+	compareStatements(t, rec.log(), []string{"BEGIN", "DELETE", "SAVEPOINT dbio_sp1", "DELETE", "ROLLBACK TO SAVEPOINT dbio_sp1",
+		"SAVEPOINT dbio_sp2", "DELETE", "RELEASE SAVEPOINT dbio_sp2", "COMMIT"})
+
+	// Sibling savepoints have different names
+	d, rec = newFakeDBIO(t)
+	err = d.WithTx(func(tx *Tx) error {
+		n1, err := tx.Begin()
+		if err != nil {
+			return err
+		}
+		n2, err := tx.Begin()
+		if err != nil {
+			return err
+		}
+		if err = n1.Rollback(); err != nil {
+			return err
+		}
+		return n2.Commit()
+	})
+	if err != nil {
+		t.Errorf("Unexpected error from transaction: %v", err)
+	}
+	compareStatements(t, rec.log(), []string{"BEGIN", "SAVEPOINT dbio_sp1", "SAVEPOINT dbio_sp2", "ROLLBACK TO SAVEPOINT dbio_sp1",
+		"RELEASE SAVEPOINT dbio_sp2", "COMMIT"})
+
+	// A transaction rolled back by a cancelled context is not reported as committed
+	d, rec = newFakeDBIO(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	err = d.WithTxCtx(ctx, func(tx *Tx) error {
+		tx.DeleteRowE("Accounts", "account_id", "1")
+		cancel()
+		// Wait for database/sql to roll back the transaction
+		for i := 0; i < 100 && countStatements(rec.log(), "ROLLBACK") == 0; i++ {
+			time.Sleep(time.Millisecond)
+		}
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Actual error %v for cancelled transaction is not equal to expected: %v", err, context.Canceled)
+	}
+	compareStatements(t, rec.log(), []string{"BEGIN", "DELETE", "ROLLBACK"})
+}
+
+func TestCancelledContext(t *testing.T) {
+	// Tests that cancelled contexts stop queries and chunked uploads (in extract.go and upload.go)
+	d, rec := newFakeDBIO(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := d.GetRowsCtx(ctx, "Accounts", "account_id", "1", "*"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Actual query error %v is not equal to expected: %v", err, context.Canceled)
+	}
+	values := [][]string{{"1", "Weasel", "15"}, {"2", "stoat", "9"}}
+	if err := d.UploadSliceCtx(ctx, "Accounts", values); !errors.Is(err, context.Canceled) {
+		t.Errorf("Actual upload error %v is not equal to expected: %v", err, context.Canceled)
+	}
+	if s := rec.log(); len(s) != 0 {
+		t.Errorf("Statements %v were submitted after the context was cancelled.", s)
+	}
+	if err := d.UploadSliceCtx(context.Background(), "Accounts", values); err != nil {
+		t.Errorf("Unexpected upload error: %v", err)
+	}
+	compareStatements(t, rec.log(), []string{"SELECT @@max_allowed_packet", "INSERT INTO `Accounts`"})
+}
+
+func TestConfig(t *testing.T) {
+	// Tests host parsing and DSN formatting (in config.go and backup.go)
+	matches := []struct {
+		host     string
+		address  string
+		dumpargs string
+	}{
+		{"", "tcp(localhost:3306)", "-hlocalhost"},
+		{"db.example.com", "tcp(db.example.com:3306)", "-hdb.example.com"},
+		{"10.0.0.5:3307", "tcp(10.0.0.5:3307)", "-h10.0.0.5 -P3307"},
+		{"[::1]:3308", "tcp([::1]:3308)", "-h::1 -P3308"},
+		{"/var/run/mysqld/mysqld.sock", "unix(/var/run/mysqld/mysqld.sock)", "--socket=/var/run/mysqld/mysqld.sock"},
+	}
+	for _, i := range matches {
+		d := NewDBIO(i.host, "test", "guest", "")
+		if d.Host != i.address {
+			t.Errorf("Actual address %s is not equal to expected: %s", d.Host, i.address)
+		}
+		if actual := strings.Join(d.getHost(), " "); actual != i.dumpargs {
+			t.Errorf("Actual mysqldump host arguments %s are not equal to expected: %s", actual, i.dumpargs)
 		}
 	}
+	// Host is parsed if Config is not set
+	d := &DBIO{Host: "tcp(10.0.0.5:3307)"}
+	if actual := strings.Join(d.getHost(), " "); actual != "-h10.0.0.5 -P3307" {
+		t.Errorf("Actual mysqldump host arguments %s without a Config are not equal to expected: -h10.0.0.5 -P3307", actual)
+	}
+	if d = NewDBIOConfig(nil); d.Host != "tcp(localhost:3306)" {
+		t.Errorf("Actual address %s for a nil Config is not equal to expected: tcp(localhost:3306)", d.Host)
+	}
+	c := NewConfig("db.example.com:3307", "test", "guest", "pass")
+	c.ParseTime = true
+	c.ReadTimeout = 30 * time.Second
+	c.Collation = "utf8mb4_unicode_ci"
+	c.Params["sql_mode"] = "'ANSI'"
+	dsn := c.FormatDSN()
+	for _, i := range []string{"guest:pass@tcp(db.example.com:3307)/test?", "parseTime=true", "readTimeout=30s", "collation=utf8mb4_unicode_ci", "charset=utf8mb4", "sql_mode="} {
+		if !strings.Contains(dsn, i) {
+			t.Errorf("DSN %s does not contain %s.", dsn, i)
+		}
+	}
+	if _, err := mysql.ParseDSN(dsn); err != nil {
+		t.Errorf("Unexpected error parsing DSN %s: %v", dsn, err)
+	}
+}
+
+func writeTestCA(t *testing.T, dir string) string {
+	// Writes a self-signed CA certificate and returns its path
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "dbIO test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ret := filepath.Join(dir, "ca.pem")
+	if err = os.WriteFile(ret, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return ret
+}
+
+func TestTLS(t *testing.T) {
+	// Tests TLS registration and mysqldump arguments
+	dir := t.TempDir()
+	ca := writeTestCA(t, dir)
+	c := NewConfig("db.example.com", "test", "guest", "")
+	cfg := c.driverConfig()
+	if err := c.registerTLS(cfg); err != nil || len(cfg.TLSConfig) > 0 {
+		t.Errorf("TLS config %q registered without TLS options: %v", cfg.TLSConfig, err)
+	}
+	if args := c.dumpTLSArgs(); len(args) > 0 {
+		t.Errorf("Unexpected mysqldump TLS arguments: %v", args)
+	}
+	c.TLSMode = TLSDisabled
+	if err := c.registerTLS(cfg); err != nil || cfg.TLSConfig != "false" {
+		t.Errorf("Actual TLS config %q is not equal to expected: false", cfg.TLSConfig)
+	}
+	c.TLSMode = ""
+	c.TLSCA = ca
+	if c.tlsMode() != TLSVerifyIdentity {
+		t.Errorf("Actual TLS mode %s is not equal to expected: %s", c.tlsMode(), TLSVerifyIdentity)
+	}
+	tc, err := c.tlsConfig()
+	if err != nil {
+		t.Fatalf("Unexpected error building TLS config: %v", err)
+	}
+	if tc.RootCAs == nil || tc.ServerName != "db.example.com" || tc.InsecureSkipVerify {
+		t.Errorf("TLS config does not verify db.example.com against the CA bundle.")
+	}
+	c.TLSMode = TLSVerifyCA
+	if tc, err = c.tlsConfig(); err != nil || tc.VerifyPeerCertificate == nil {
+		t.Errorf("TLS config does not verify the certificate chain: %v", err)
+	}
+	cfg = c.driverConfig()
+	if err = c.registerTLS(cfg); err != nil || cfg.TLSConfig != c.tlsName() {
+		t.Errorf("Actual TLS config %q is not equal to expected: %s", cfg.TLSConfig, c.tlsName())
+	}
+	if dsn := cfg.FormatDSN(); !strings.Contains(dsn, "tls="+c.tlsName()) {
+		t.Errorf("DSN %s does not contain registered TLS config.", dsn)
+	}
+	expected := fmt.Sprintf("--ssl-mode=VERIFY_CA --ssl-ca=%s", ca)
+	if actual := strings.Join(c.dumpTLSArgs(), " "); actual != expected {
+		t.Errorf("Actual mysqldump TLS arguments %s are not equal to expected: %s", actual, expected)
+	}
+	for _, i := range []*Config{
+		{TLSMode: TLSVerifyCA},
+		{TLSMode: "SOMETIMES"},
+		{TLSCA: filepath.Join(dir, "missing.pem")},
+		{TLSCA: ca, TLSCert: filepath.Join(dir, "missing.pem")},
+	} {
+		if err := i.registerTLS(mysql.NewConfig()); !errors.Is(err, ErrConnection) {
+			t.Errorf("Actual error %v for invalid TLS options does not wrap %v.", err, ErrConnection)
+		}
+	}
+}
+
+func TestCredentials(t *testing.T) {
+	// Tests credential providers (in credentials.go)
+	ctx := context.Background()
+	dir := t.TempDir()
+	cnf := filepath.Join(dir, "my.cnf")
+	content := "# comment\n[mysqld]\nuser = mysql\n\n[client]\nuser = backup\npassword = \"p#ss word\" # comment\nhost=db.example.com\nport = 3307\n"
+	if err := os.WriteFile(cnf, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	pwfile := filepath.Join(dir, "password")
+	if err := os.WriteFile(pwfile, []byte("secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("MYSQL_USER", "envuser")
+	t.Setenv("MYSQL_PWD", "")
+	t.Setenv("MYSQL_TCP_PORT", "")
+	t.Setenv("DBIO_TEST_PWD", "envpass")
+	matches := []struct {
+		provider CredentialProvider
+		current  Credentials
+		expected Credentials
+	}{
+		{OptionFileProvider{Path: cnf}, Credentials{}, Credentials{User: "backup", Password: "p#ss word", Host: "db.example.com", Port: 3307}},
+		{OptionFileProvider{Path: cnf}, Credentials{User: "admin"}, Credentials{User: "admin", Password: "p#ss word", Host: "db.example.com", Port: 3307}},
+		{OptionFileProvider{Path: cnf, Groups: []string{"mysqld"}}, Credentials{}, Credentials{User: "mysql"}},
+		{EnvProvider{}, Credentials{}, Credentials{User: "envuser"}},
+		{EnvProvider{PasswordVar: "DBIO_TEST_PWD"}, Credentials{User: "admin"}, Credentials{User: "admin", Password: "envpass"}},
+		{PasswordFileProvider{Path: pwfile}, Credentials{User: "admin"}, Credentials{User: "admin", Password: "secret"}},
+		{ChainProvider{EnvProvider{}, PasswordFileProvider{Path: pwfile}}, Credentials{}, Credentials{User: "envuser", Password: "secret"}},
+	}
+	for _, i := range matches {
+		actual, err := i.provider.Credentials(ctx, i.current)
+		if err != nil {
+			t.Errorf("Unexpected error from %T: %v", i.provider, err)
+		} else if actual != i.expected {
+			t.Errorf("Actual credentials %+v from %T are not equal to expected: %+v", actual, i.provider, i.expected)
+		}
+	}
+	for _, i := range []CredentialProvider{OptionFileProvider{Path: filepath.Join(dir, "missing.cnf")}, PasswordFileProvider{Path: filepath.Join(dir, "missing")}} {
+		if _, err := i.Credentials(ctx, Credentials{}); !errors.Is(err, ErrCredentials) {
+			t.Errorf("Actual error %v from %T does not wrap %v.", err, i, ErrCredentials)
+		}
+	}
+	// Missing credentials return an error instead of prompting
+	c := NewConfig("", "test", "", "")
+	c.NonInteractive = true
+	c.Credentials = PasswordFileProvider{Path: pwfile}
+	if _, err := ConnectConfig(c); !errors.Is(err, ErrCredentials) || !errors.Is(err, ErrConnection) {
+		t.Errorf("Actual error %v does not wrap %v and %v.", err, ErrCredentials, ErrConnection)
+	}
+	c = NewConfig("", "test", "", "")
+	c.NonInteractive = true
+	c.Credentials = OptionFileProvider{Path: cnf}
+	d := NewDBIOConfig(c)
+	if err := d.resolveCredentials(ctx); err != nil {
+		t.Fatalf("Unexpected error resolving credentials: %v", err)
+	}
+	if d.User != "backup" || d.Password != "p#ss word" || d.Host != "tcp(db.example.com:3307)" {
+		t.Errorf("Credentials %s@%s were not read from option file.", d.User, d.Host)
+	}
+}
+
+func TestPool(t *testing.T) {
+	// Tests pool options and the health checker (in pool.go)
+	d, rec := newFakeDBIO(t)
+	d.Config.MaxOpenConns = 4
+	d.Config.MaxIdleConns = 2
+	d.Config.applyPool(d.DB)
+	if s := d.Stats(); s.MaxOpenConnections != 4 || !s.Healthy {
+		t.Errorf("Actual stats %s do not match config.", s)
+	}
+	changes := make(chan bool, 4)
+	opts := HealthCheck{Interval: 5 * time.Millisecond, OnChange: func(healthy bool, err error) {
+		changes <- healthy
+	}}
+	if err := d.StartHealthCheck(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+	defer d.StopHealthCheck()
+	if err := d.StartHealthCheck(context.Background(), opts); err == nil {
+		t.Error("Second health check started without error.")
+	}
+	rec.Lock()
+	rec.fail["PING"] = driver.ErrBadConn
+	rec.Unlock()
+	for _, expected := range []bool{false, true} {
+		select {
+		case healthy := <-changes:
+			if healthy != expected {
+				t.Errorf("Actual health state %v is not equal to expected: %v", healthy, expected)
+			}
+			if s := d.Stats(); s.Healthy != expected {
+				t.Errorf("Actual stats %s do not match health state %v.", s, expected)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Health state was not changed to %v.", expected)
+		}
+		rec.Lock()
+		delete(rec.fail, "PING")
+		rec.Unlock()
+	}
+	d.StopHealthCheck()
+	if s := d.Stats(); !s.Healthy || !s.LastCheck.IsZero() {
+		t.Errorf("Stats %s were not reset after stopping health check.", s)
+	}
+	// Stats may be read while health checks start and stop
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			d.Stats()
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		if err := d.StartHealthCheck(context.Background(), opts); err != nil {
+			t.Error(err)
+		}
+		d.StopHealthCheck()
+	}
+	<-done
+	// Idle connections are flushed without a Config
+	c := &DBIO{DB: d.DB}
+	c.flushIdle()
+	// The checker is cleared when ctx is cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := d.StartHealthCheck(ctx, opts); err != nil {
+		t.Fatal(err)
+	}
+	h := d.runningCheck()
+	cancel()
+	<-h.done
+	if d.runningCheck() != nil {
+		t.Error("Health checker was not cleared after its context was cancelled.")
+	}
+	if err := d.StartHealthCheck(context.Background(), opts); err != nil {
+		t.Errorf("Health check could not be restarted after its context was cancelled: %v", err)
+	}
+	d.StopHealthCheck()
+}
+
+func countStatements(statements []string, key string) int {
+	// Returns the number of statements containing key
+	var ret int
+	for _, i := range statements {
+		if strings.Contains(i, key) {
+			ret++
+		}
+	}
+	return ret
+}
+
+func TestRetry(t *testing.T) {
+	// Tests retries of transient errors (in retry.go)
+	deadlock := &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+	duplicate := &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}
+	matches := []struct {
+		err      error
+		failures int
+		attempts int
+		expected int
+		success  bool
+	}{
+		{deadlock, 2, 3, 3, true},
+		{deadlock, 2, 2, 2, false},
+		{driver.ErrBadConn, 1, 3, 2, true},
+		{duplicate, 1, 3, 1, false},
+	}
+	for _, i := range matches {
+		d, rec := newFakeDBIO(t)
+		d.Retry = &RetryPolicy{MaxAttempts: i.attempts, BaseDelay: time.Millisecond}
+		rec.fail["INSERT"] = i.err
+		rec.failCount["INSERT"] = i.failures
+		err := d.InsertCtx(context.Background(), "Patient", "INSERT INTO Patient (ID) VALUES (1);")
+		if (err == nil) != i.success {
+			t.Errorf("Actual error %v after %d failures with %d attempts does not match expected success: %v", err, i.failures, i.attempts, i.success)
+		}
+		if actual := countStatements(rec.log(), "INSERT"); actual != i.expected {
+			t.Errorf("Actual number of attempts %d is not equal to expected: %d", actual, i.expected)
+		}
+	}
+	// Statements are not retried within a transaction
+	d, rec := newFakeDBIO(t)
+	d.Retry = DefaultRetryPolicy()
+	rec.fail["DELETE"] = deadlock
+	rec.failCount["DELETE"] = 1
+	d.WithTx(func(tx *Tx) error {
+		return tx.DeleteRowCtx(context.Background(), "Patient", "ID", "1")
+	})
+	if actual := countStatements(rec.log(), "DELETE"); actual != 1 {
+		t.Errorf("Statement was submitted %d times within transaction.", actual)
+	}
+	// Chunked uploads resume at the failed chunk
+	d, rec = newFakeDBIO(t)
+	d.Retry = &RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+	d.MaxPacket = 1 << 20
+	var values [][]string
+	for _, i := range []string{"first", "second", "third"} {
+		values = append(values, []string{i + strings.Repeat("x", 600000), "F", "1", "cat"})
+	}
+	rec.fail["second"] = deadlock
+	rec.failCount["second"] = 1
+	if err := d.UploadSliceCtx(context.Background(), "Patient", values); err != nil {
+		t.Errorf("Unexpected error uploading slice: %v", err)
+	}
+	for k, v := range map[string]int{"first": 1, "second": 2, "third": 1} {
+		var actual int
+		for _, i := range rec.args {
+			if argsContain(i, k) {
+				actual++
+			}
+		}
+		if actual != v {
+			t.Errorf("Chunk %s was submitted %d times instead of %d.", k, actual, v)
+		}
+	}
+	p := &RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+	for attempt, max := range []time.Duration{10, 20, 40, 50, 50} {
+		max *= time.Millisecond
+		if actual := p.backoff(attempt + 1); actual < max/2 || actual > max {
+			t.Errorf("Backoff %v for attempt %d is outside of expected range: %v - %v", actual, attempt+1, max/2, max)
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+	// Tests statements built by the SELECT query builder (in select.go)
+	d := getTestDBIO()
+	matches := []struct {
+		query *SelectQuery
+		cmd   string
+		args  []interface{}
+	}{
+		{d.Select().From("Patient"), "SELECT * FROM `Patient`;", nil},
+		{d.Select("ID", "age").From("Patient").Where("Sex", "=", "F").And("Age", ">=", 7).Or("Species", "like", "%cat%"),
+			"SELECT `ID`,`Age` FROM `Patient` WHERE `Sex` = ? AND `Age` >= ? OR `Species` LIKE ?;", []interface{}{"F", 7, "%cat%"}},
+		{d.Select("Species").Distinct().From("Patient").Where("ID", "in", []string{"1", "2"}).And("Age", "is not null", nil).OrderByDesc("Age").OrderBy("ID").Limit(10).Offset(20),
+			"SELECT DISTINCT `Species` FROM `Patient` WHERE `ID` IN (?,?) AND `Age` IS NOT NULL ORDER BY `Age` DESC, `ID` LIMIT ? OFFSET ?;", []interface{}{"1", "2", 10, 20}},
+		{d.Select("ID").From("Patient").Offset(5), "SELECT `ID` FROM `Patient` LIMIT 18446744073709551615 OFFSET ?;", []interface{}{5}},
+	}
+	for _, i := range matches {
+		cmd, args, err := i.query.SQL()
+		if err != nil {
+			t.Errorf("Unexpected error building %s: %v", i.cmd, err)
+		} else if cmd != i.cmd {
+			t.Errorf("Actual statement %s is not equal to expected: %s", cmd, i.cmd)
+		} else if fmt.Sprint(args) != fmt.Sprint(i.args) {
+			t.Errorf("Actual arguments %v are not equal to expected: %v", args, i.args)
+		}
+	}
+	invalid := map[*SelectQuery]error{
+		d.Select("ID"):                                                  ErrInvalidQuery,
+		d.Select("ID").From("Patients"):                                 ErrUnknownTable,
+		d.Select("ID; DROP TABLE Patient").From("Patient"):              ErrUnknownColumn,
+		d.Select().From("Patient").Where("Age", "= 1 OR 1 =", 1):        ErrInvalidQuery,
+		d.Select().From("Patient").Where("ID", "IN", "1,2"):             ErrInvalidQuery,
+		d.Select().From("Patient").Where("Age", ">", 1).OrderBy("Age`"): ErrUnknownColumn,
+	}
+	for k, v := range invalid {
+		if _, _, err := k.SQL(); !errors.Is(err, v) {
+			t.Errorf("Actual error %v is not equal to expected: %v", err, v)
+		}
+	}
+	d, rec := newFakeDBIO(t)
+	rec.results["SELECT `ID`,`Sex`"] = fakeResult{[]string{"ID", "Sex"}, [][]driver.Value{{[]byte("1"), []byte("F")}, {[]byte("2"), []byte("M")}}, nil}
+	maps, err := d.Select("ID", "Sex").From("Patient").Where("Age", ">", 1).Maps()
+	if err != nil {
+		t.Fatalf("Unexpected error selecting maps: %v", err)
+	}
+	if len(maps) != 2 || maps[0]["ID"] != "1" || maps[1]["Sex"] != "M" {
+		t.Errorf("Actual maps %v are not equal to expected rows.", maps)
+	}
+}
+
+func TestJoin(t *testing.T) {
+	// Tests join clauses and foreign key discovery (in join.go)
+	d := getTestDBIO()
+	d.Columns["Diagnosis"] = "ID,patient_id,Diagnosis"
+	d.Columns["Tumor"] = "ID,patient_id,Location"
+	d.ForeignKeys = []ForeignKey{
+		{"diagnosis_ibfk_1", "Diagnosis", "patient_id", "Patient", "ID"},
+		{"tumor_ibfk_1", "Tumor", "patient_id", "Patient", "ID"},
+	}
+	matches := []struct {
+		query *SelectQuery
+		cmd   string
+	}{
+		{d.Select("Patient.ID", "Diagnosis").From("Patient").Join("Diagnosis", "ID", "patient_id").Where("Age", ">", 1).OrderBy("Diagnosis.ID"),
+			"SELECT `Patient`.`ID` AS `Patient.ID`,`Diagnosis`.`Diagnosis` AS `Diagnosis.Diagnosis` FROM `Patient` JOIN `Diagnosis` ON `Patient`.`ID` = `Diagnosis`.`patient_id` WHERE `Patient`.`Age` > ? ORDER BY `Diagnosis`.`ID`;"},
+		{d.Select("Species,Location").From("Patient").LeftJoin("Tumor", "", ""),
+			"SELECT `Patient`.`Species` AS `Patient.Species`,`Tumor`.`Location` AS `Tumor.Location` FROM `Patient` LEFT JOIN `Tumor` ON `Tumor`.`patient_id` = `Patient`.`ID`;"},
+		{d.Select().From("Diagnosis").Join("Patient", "", ""),
+			"SELECT `Diagnosis`.`ID` AS `Diagnosis.ID`,`Diagnosis`.`patient_id` AS `Diagnosis.patient_id`,`Diagnosis`.`Diagnosis` AS `Diagnosis.Diagnosis`,`Patient`.`ID` AS `Patient.ID`,`Patient`.`Sex` AS `Patient.Sex`,`Patient`.`Age` AS `Patient.Age`,`Patient`.`Species` AS `Patient.Species` FROM `Diagnosis` JOIN `Patient` ON `Diagnosis`.`patient_id` = `Patient`.`ID`;"},
+	}
+	for _, i := range matches {
+		if cmd, _, err := i.query.SQL(); err != nil {
+			t.Errorf("Unexpected error building %s: %v", i.cmd, err)
+		} else if cmd != i.cmd {
+			t.Errorf("Actual statement %s is not equal to expected: %s", cmd, i.cmd)
+		}
+	}
+	invalid := map[*SelectQuery]error{
+		d.Select("ID").From("Patient").Join("Diagnosis", "ID", "patient_id"):                  ErrInvalidQuery,
+		d.Select("Tumor.ID").From("Patient").Join("Diagnosis", "ID", "patient_id"):            ErrUnknownTable,
+		d.Select().From("Diagnosis").Join("Tumor", "", ""):                                    ErrInvalidQuery,
+		d.Select().From("Patient").Join("Diagnosis", "", "").Join("Tumor", "Patient.ID", "x"): ErrUnknownColumn,
+	}
+	for k, v := range invalid {
+		if _, _, err := k.SQL(); !errors.Is(err, v) {
+			t.Errorf("Actual error %v is not equal to expected: %v", err, v)
+		}
+	}
+	d, rec := newFakeDBIO(t)
+	d.Columns["Diagnosis"] = "ID,patient_id,Diagnosis"
+	rec.results["KEY_COLUMN_USAGE"] = fakeResult{[]string{"CONSTRAINT_NAME", "TABLE_NAME", "COLUMN_NAME", "REFERENCED_TABLE_NAME", "REFERENCED_COLUMN_NAME"},
+		[][]driver.Value{{"diagnosis_ibfk_1", "Diagnosis", "patient_id", "Patient", "ID"}}, nil}
+	expected := "SELECT `Patient`.`ID` AS `Patient.ID` FROM `Patient` JOIN `Diagnosis` ON `Diagnosis`.`patient_id` = `Patient`.`ID`;"
+	if cmd, _, err := d.Select("Patient.ID").From("Patient").Join("Diagnosis", "", "").SQL(); err != nil || cmd != expected {
+		t.Errorf("Actual statement %s from discovered foreign keys is not equal to expected: %s (%v)", cmd, expected, err)
+	}
+}
+
+type testBase struct {
+	ID int64
+}
+
+type testPatient struct {
+	testBase
+	Sex     string
+	Age     *int
+	Species sql.NullString `db:"Species"`
+	Note    string         `db:"-"`
+}
+
+func TestQueryStructs(t *testing.T) {
+	// Tests scanning results into structs (in scan.go)
+	d, rec := newFakeDBIO(t)
+	rec.results["FROM `Patient`"] = fakeResult{[]string{"ID", "Sex", "Age", "Species"}, [][]driver.Value{
+		{int64(1), []byte("F"), int64(7), []byte("cat")},
+		{int64(2), []byte("M"), nil, nil},
+	}, nil}
+	rec.results["Accounts"] = fakeResult{[]string{"account_id", "Account"}, [][]driver.Value{{int64(1), []byte("a")}}, nil}
+	patients, err := GetTableAs[testPatient](d, "Patient")
+	if err != nil {
+		t.Fatalf("Unexpected error scanning structs: %v", err)
+	}
+	if len(patients) != 2 {
+		t.Fatalf("Actual number of rows %d is not equal to expected: 2", len(patients))
+	}
+	if p := patients[0]; p.ID != 1 || p.Sex != "F" || p.Age == nil || *p.Age != 7 || p.Species.String != "cat" {
+		t.Errorf("Actual struct %+v does not match first row.", p)
+	}
+	if p := patients[1]; p.ID != 2 || p.Age != nil || p.Species.Valid {
+		t.Errorf("Actual struct %+v does not contain NULL values.", p)
+	}
+	selected, err := SelectAs[testPatient](d.Select("ID", "Sex").From("Patient").Where("Age", ">", 1))
+	if err != nil || len(selected) != 2 {
+		t.Errorf("Unexpected result %+v selecting structs: %v", selected, err)
+	}
+	if _, err = Query[testPatient](d, "SELECT * FROM Accounts;"); !errors.Is(err, ErrUnmappedColumn) || !strings.Contains(err.Error(), "account_id, Account") {
+		t.Errorf("Actual error %v does not list unmapped columns.", err)
+	}
+	if _, err = Query[string](d, "SELECT * FROM Accounts;"); !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("Actual error %v for non-struct type is not equal to expected: %v", err, ErrInvalidQuery)
+	}
+	// Times are parsed the same as by GetColumn when ParseTime is false
+	type visit struct {
+		Admitted   time.Time
+		Discharged *time.Time
+		Followup   sql.NullTime
+	}
+	rec.results["FROM Visits"] = fakeResult{[]string{"Admitted", "Discharged", "Followup"}, [][]driver.Value{
+		{[]byte("2020-03-14 15:09:26"), nil, []byte("2020-04-01")},
+	}, nil}
+	visits, err := Query[visit](d, "SELECT * FROM Visits;")
+	if err != nil || len(visits) != 1 {
+		t.Fatalf("Unexpected result %+v scanning times: %v", visits, err)
+	}
+	admitted, followup := time.Date(2020, 3, 14, 15, 9, 26, 0, time.UTC), time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC)
+	if v := visits[0]; !v.Admitted.Equal(admitted) || v.Discharged != nil || !v.Followup.Valid || !v.Followup.Time.Equal(followup) {
+		t.Errorf("Actual struct %+v does not match expected times.", v)
+	}
+}
+
+func TestToSlice(t *testing.T) {
+	// Tests conversion of driver values to strings (in values.go)
+	d, rec := newFakeDBIO(t)
+	stamp := time.Date(2020, 3, 14, 15, 9, 26, 535000000, time.UTC)
+	rec.results["Patient"] = fakeResult{
+		[]string{"ID", "Age", "Weight", "Ratio", "Cost", "Born", "Updated", "Created", "Flag", "Count", "Name", "Note"},
+		[][]driver.Value{
+			{int64(-1), []byte("7"), float64(1000000), float32(0.1), []byte("12.50"), stamp, stamp, time.Time{}, []byte{1, 0}, uint64(18446744073709551615), "cat", nil},
+			{int64(2), nil, float64(2.5), float32(3), []byte("0.00"), stamp, stamp.Truncate(time.Second), stamp, []byte{0}, uint64(0), []byte("dog"), []byte("")},
+		},
+		[]string{"BIGINT", "INT", "DOUBLE", "FLOAT", "DECIMAL", "DATE", "DATETIME", "TIMESTAMP", "BIT", "BIGINT", "VARCHAR", "TEXT"},
+	}
+	expected := [][]string{
+		{"-1", "7", "1000000", "0.1", "12.50", "2020-03-14", "2020-03-14 15:09:26.535", "0000-00-00 00:00:00", "256", "18446744073709551615", "cat", ""},
+		{"2", "", "2.5", "3", "0.00", "2020-03-14", "2020-03-14 15:09:26", "2020-03-14 15:09:26.535", "0", "0", "dog", ""},
+	}
+	for _, null := range []string{"", NullNA, NullSentinel} {
+		d.NullValue = null
+		expected[0][11] = null
+		expected[1][1] = null
+		actual, err := d.ExecuteE("SELECT * FROM Patient;")
+		if err != nil {
+			t.Fatalf("Unexpected error executing query: %v", err)
+		}
+		if fmt.Sprintf("%q", actual) != fmt.Sprintf("%q", expected) {
+			t.Errorf("Actual rows %q are not equal to expected: %q", actual, expected)
+		}
+	}
+}
+
+func TestResultSet(t *testing.T) {
+	// Tests result set helpers (in result.go)
+	d, rec := newFakeDBIO(t)
+	rec.results["Patient"] = fakeResult{[]string{"ID", "Sex", "Species"}, [][]driver.Value{
+		{int64(1), []byte("F"), []byte("cat")},
+		{int64(22), nil, []byte("rhinocéros")},
+	}, []string{"INT", "CHAR", "VARCHAR"}}
+	r, err := d.GetTableResult("Patient")
+	if err != nil {
+		t.Fatalf("Unexpected error getting result set: %v", err)
+	}
+	if r.Len() != 2 || strings.Join(r.Names(), ",") != "ID,Sex,Species" || r.Columns[0].Type != "INT" || !r.Columns[0].Nullable {
+		t.Errorf("Actual columns %+v do not match query.", r.Columns)
+	}
+	if v, ok := r.Value(1, "species"); !ok || v != "rhinocéros" {
+		t.Errorf("Actual value %s is not equal to expected: rhinocéros", v)
+	}
+	if _, ok := r.Value(2, "ID"); ok {
+		t.Error("Value returned for missing row.")
+	}
+	if !r.IsNull(1, "Sex") || r.IsNull(0, "Sex") {
+		t.Error("NULL values were not recorded.")
+	}
+	if m := r.Maps(); m[1]["ID"] != "22" {
+		t.Errorf("Actual maps %v are not equal to expected rows.", m)
+	}
+	p, err := r.Project("Species", "ID")
+	if err != nil {
+		t.Fatalf("Unexpected error projecting columns: %v", err)
+	}
+	if err = p.Rename(map[string]string{"Species": "Name"}); err != nil {
+		t.Fatalf("Unexpected error renaming columns: %v", err)
+	}
+	expected := `+------------+----+
+| Name       | ID |
++------------+----+
+| cat        | 1  |
+| rhinocéros | 22 |
++------------+----+
+`
+	if p.String() != expected {
+		t.Errorf("Actual table:\n%s\nis not equal to expected:\n%s", p.String(), expected)
+	}
+	if strings.Join(p.Column("ID"), ",") != "1,22" || r.Columns[2].Name != "Species" {
+		t.Error("Projected result set does not contain the original values.")
+	}
+	if _, err = r.Project("Age"); !errors.Is(err, ErrUnknownColumn) {
+		t.Errorf("Actual error %v is not equal to expected: %v", err, ErrUnknownColumn)
+	}
+}
+
+func TestStream(t *testing.T) {
+	// Tests streaming rows (in stream.go)
+	d, rec := newFakeDBIO(t)
+	d.NullValue = NullSentinel
+	rec.results["SELECT * FROM `Patient`"] = fakeResult{[]string{"ID", "Species"}, [][]driver.Value{
+		{int64(1), []byte("cat")},
+		{int64(2), []byte("dog, domestic")},
+		{int64(3), []byte("cat")},
+		{int64(4), nil},
+	}, nil}
+	rec.results["SELECT `Species`,COUNT(*) FROM `Patient` GROUP BY `Species`"] = fakeResult{[]string{"Species", "COUNT(*)"}, [][]driver.Value{
+		{[]byte("cat"), int64(2)},
+		{[]byte("dog"), int64(1)},
+		{nil, int64(1)},
+	}, nil}
+	var ids []string
+	err := d.EachRow("Patient", "*", func(row []string) error {
+		ids = append(ids, row[0])
+		if len(ids) == 2 {
+			return ErrStop
+		}
+		return nil
+	})
+	if err != nil || strings.Join(ids, ",") != "1,2" {
+		t.Errorf("Iteration did not stop after second row: %v (%v)", ids, err)
+	}
+	failed := errors.New("failed")
+	if err = d.EachRow("Patient", "*", func(row []string) error { return failed }); !errors.Is(err, failed) {
+		t.Errorf("Actual error %v is not equal to expected: %v", err, failed)
+	}
+	occ, err := d.GetNumOccurancesE("Patient", "Species")
+	if err != nil || occ["cat"] != 2 || occ[NullSentinel] != 1 {
+		t.Errorf("Actual occurances %v are not equal to expected counts.", occ)
+	}
+	var b strings.Builder
+	if err = d.ExportTable("Patient", &b, ','); err != nil {
+		t.Fatalf("Unexpected error exporting table: %v", err)
+	}
+	expected := "ID,Species\n1,cat\n2,\"dog, domestic\"\n3,cat\n4,\\N\n"
+	if b.String() != expected {
+		t.Errorf("Actual export %q is not equal to expected: %q", b.String(), expected)
+	}
+	it, err := d.StreamTable("Patient", "*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+	for it.Next() {
+		if (it.Row()[0] == "4") != it.IsNull(1) {
+			t.Errorf("NULL value was not recorded for row %v.", it.Row())
+		}
+	}
+	if it.Err() != nil {
+		t.Errorf("Unexpected error streaming rows: %v", it.Err())
+	}
+}
+
+func TestPaginator(t *testing.T) {
+	// Tests keyset pagination (in paginate.go)
+	d := getTestDBIO()
+	p, err := d.NewPaginator("Patient", PageOptions{Target: "Species", Keys: []string{"sex", "ID"}, Desc: true, Size: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := p.token()
+	next.Keys = []string{"M", "7"}
+	token := p.encode(next)
+	cmd, args, err := p.SQL(token)
+	expected := "SELECT `Species`,`Sex`,`ID` FROM `Patient` WHERE (`Sex` < ?) OR (`Sex` = ? AND `ID` < ?) ORDER BY `Sex` DESC, `ID` DESC LIMIT ?;"
+	if err != nil || cmd != expected {
+		t.Errorf("Actual statement %s is not equal to expected: %s (%v)", cmd, expected, err)
+	} else if fmt.Sprint(args) != "[M M 7 11]" {
+		t.Errorf("Actual arguments %v are not equal to expected.", args)
+	}
+	// Tokens for other tables or orders are rejected
+	asc := pageToken{Table: "Patient", Names: []string{"Sex", "ID"}, Keys: []string{"M", "7"}}
+	other := pageToken{Table: "Patient", Names: []string{"ID", "Sex"}, Desc: true, Keys: []string{"7", "M"}}
+	for _, i := range []string{"not a token", p.encode(pageToken{Table: "Accounts", Keys: []string{"1", "2"}}), p.encode(pageToken{Table: "Patient", Keys: []string{"1"}}), p.encode(asc), p.encode(other)} {
+		if _, _, err = p.SQL(i); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("Actual error %v for token %q is not equal to expected: %v", err, i, ErrInvalidQuery)
+		}
+	}
+	if _, err = d.NewPaginator("Patient", PageOptions{Keys: []string{"ID`"}}); !errors.Is(err, ErrUnknownColumn) {
+		t.Errorf("Actual error %v is not equal to expected: %v", err, ErrUnknownColumn)
+	}
+
+	d, rec := newFakeDBIO(t)
+	rec.results["CONSTRAINT_NAME = 'PRIMARY'"] = fakeResult{[]string{"COLUMN_NAME"}, [][]driver.Value{{[]byte("ID")}}, nil}
+	rec.results["SELECT *,`ID` FROM `Patient`"] = fakeResult{[]string{"ID", "Sex", "Age", "Species", "ID"}, [][]driver.Value{
+		{int64(1), []byte("F"), int64(3), []byte("cat"), int64(1)},
+		{int64(2), []byte("M"), int64(5), []byte("dog"), int64(2)},
+		{int64(4), []byte("F"), int64(9), []byte("cat"), int64(4)},
+	}, nil}
+	if p, err = d.NewPaginator("Patient", PageOptions{Size: 2}); err != nil {
+		t.Fatal(err)
+	} else if fmt.Sprint(p.Keys()) != "[ID]" {
+		t.Errorf("Actual keys %v are not equal to primary key.", p.Keys())
+	}
+	page, err := p.Page("")
+	if err != nil {
+		t.Fatal(err)
+	} else if len(page.Rows) != 2 || len(page.Columns) != 4 || len(page.Rows[1]) != 4 || len(page.Next) == 0 {
+		t.Fatalf("Actual page %v does not contain 2 rows and a continuation token.", page)
+	}
+	if _, err = p.Page(page.Next); err != nil {
+		t.Fatal(err)
+	}
+	if last := rec.args[len(rec.args)-1]; fmt.Sprint(last) != "[2 3]" {
+		t.Errorf("Actual arguments %v for the second page are not equal to expected.", last)
+	}
+	rec.results["SELECT *,`ID` FROM `Patient`"] = fakeResult{[]string{"ID", "Sex", "Age", "Species", "ID"}, [][]driver.Value{{int64(1), []byte("F"), int64(3), []byte("cat"), int64(1)}}, nil}
+	if page, err = p.Page(""); err != nil || page.Next != "" {
+		t.Errorf("Continuation token %q was returned for the last page (%v).", page.Next, err)
+	}
+
+	t.Run("offset", func(t *testing.T) {
+		// Tables without a primary key are read using OFFSET
+		d, rec := newFakeDBIO(t)
+		rec.results["SELECT `ID` FROM `Patient`"] = fakeResult{[]string{"ID"}, [][]driver.Value{{int64(1)}, {int64(2)}, {int64(3)}}, nil}
+		p, err := d.NewPaginator("Patient", PageOptions{Target: "ID", Size: 2})
+		if err != nil {
+			t.Fatal(err)
+		} else if len(p.Keys()) != 0 {
+			t.Errorf("Actual keys %v are not empty.", p.Keys())
+		}
+		page, err := p.Page("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		cmd, args, err := p.SQL(page.Next)
+		expected := "SELECT `ID` FROM `Patient` ORDER BY `ID` LIMIT ? OFFSET ?;"
+		if err != nil || cmd != expected || fmt.Sprint(args) != "[3 2]" {
+			t.Errorf("Actual statement %s %v is not equal to expected: %s (%v)", cmd, args, expected, err)
+		}
+		if p, err = d.NewPaginator("Patient", PageOptions{Desc: true}); err != nil {
+			t.Fatal(err)
+		}
+		cmd, _, err = p.SQL("")
+		expected = "SELECT * FROM `Patient` ORDER BY `ID` DESC, `Sex` DESC, `Age` DESC, `Species` DESC LIMIT ?;"
+		if err != nil || cmd != expected {
+			t.Errorf("Actual statement %s is not equal to expected: %s (%v)", cmd, expected, err)
+		}
+	})
+}
+
+func TestAggregate(t *testing.T) {
+	// Tests aggregate queries (in aggregate.go)
+	d := getTestDBIO()
+	matches := []struct {
+		query  *AggregateQuery
+		fn     string
+		column string
+		cmd    string
+		args   []interface{}
+	}{
+		{d.Aggregate("Patient"), "COUNT", "", "SELECT COUNT(*) FROM `Patient`;", nil},
+		{d.Aggregate("Patient").Where("Age", ">", 2).GroupBy("species", "Sex").Having(">=", 5), "AVG", "age",
+			"SELECT `Species`,`Sex`,COUNT(*),AVG(`Age`) FROM `Patient` WHERE `Age` > ? GROUP BY `Species`,`Sex` HAVING AVG(`Age`) >= ?;", []interface{}{2, 5}},
+		{d.Aggregate("Patient").GroupBy("Species").Having(">", 1).Top(3), "COUNT", "",
+			"SELECT `Species`,COUNT(*) FROM `Patient` GROUP BY `Species` HAVING COUNT(*) > ? ORDER BY COUNT(*) DESC, `Species` LIMIT ?;", []interface{}{1, 3}},
+	}
+	for _, i := range matches {
+		cmd, args, err := i.query.sql(context.Background(), i.fn, i.column)
+		if err != nil {
+			t.Errorf("Unexpected error building %s: %v", i.cmd, err)
+		} else if cmd != i.cmd {
+			t.Errorf("Actual statement %s is not equal to expected: %s", cmd, i.cmd)
+		} else if fmt.Sprint(args) != fmt.Sprint(i.args) {
+			t.Errorf("Actual arguments %v are not equal to expected: %v", args, i.args)
+		}
+	}
+	invalid := map[*AggregateQuery]error{
+		d.Aggregate("Patients"):                            ErrUnknownTable,
+		d.Aggregate("Patient").GroupBy("Species`"):         ErrUnknownColumn,
+		d.Aggregate("Patient").Having("> 1 OR 1 =", 1):     ErrInvalidQuery,
+		d.Aggregate("Patient").Where("Age", "IN", "1,2,3"): ErrInvalidQuery,
+	}
+	for k, v := range invalid {
+		if _, err := k.Count(); !errors.Is(err, v) {
+			t.Errorf("Actual error %v is not equal to expected: %v", err, v)
+		}
+	}
+
+	d, rec := newFakeDBIO(t)
+	d.NullValue = NullNA
+	rec.results["SELECT `Species`,COUNT(*),MAX(`Age`) FROM"] = fakeResult{[]string{"Species", "COUNT(*)", "MAX(`Age`)"}, [][]driver.Value{
+		{[]byte("cat"), int64(3), []byte("12.5")},
+		{nil, int64(2), nil},
+	}, nil}
+	groups, err := d.Aggregate("Patient").GroupBy("Species").Max("Age")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Group{{[]string{"cat"}, 3, 12.5, false}, {[]string{"NA"}, 2, 0, true}}
+	if !reflect.DeepEqual(groups, expected) {
+		t.Errorf("Actual groups %v are not equal to expected: %v", groups, expected)
+	}
+	rec.results["SELECT `Sex`,COUNT(*) FROM"] = fakeResult{[]string{"Sex", "COUNT(*)"}, [][]driver.Value{{[]byte("F"), int64(4)}, {[]byte("M"), int64(1)}}, nil}
+	counts, err := d.GroupCount("Patient", "Sex", Filter{"Species", "=", "cat"})
+	if err != nil || counts["F"] != 4 || counts["M"] != 1 {
+		t.Errorf("Actual counts %v are not equal to expected (%v).", counts, err)
+	}
+	statements := rec.log()
+	if cmd := statements[len(statements)-1]; !strings.Contains(cmd, "WHERE `Species` = ? GROUP BY `Sex`") {
+		t.Errorf("Filter was not applied to group count: %s", cmd)
+	}
+}
+
+func TestGetColumn(t *testing.T) {
+	// Tests typed column extraction (in column.go)
+	d, rec := newFakeDBIO(t)
+	d.Columns["Patient"] = "ID,Sex,Age,Species,Admitted,Neutered"
+	rec.results["SELECT `Age` FROM"] = fakeResult{[]string{"Age"}, [][]driver.Value{{[]byte("2.5")}, {int64(7)}, {nil}, {[]byte("old")}}, nil}
+	rec.results["SELECT `Admitted` FROM"] = fakeResult{[]string{"Admitted"}, [][]driver.Value{
+		{[]byte("2020-01-02 03:04:05.5")},
+		{[]byte("2021-06-30")},
+		{time.Date(2022, 3, 4, 0, 0, 0, 0, time.UTC)},
+		{nil},
+	}, nil}
+	rec.results["SELECT `Neutered` FROM"] = fakeResult{[]string{"Neutered"}, [][]driver.Value{{int64(1)}, {int64(0)}, {[]byte("1")}}, nil}
+	ages, err := d.GetColumnFloat("Patient", "Age")
+	var cerr *ConversionError
+	if !errors.As(err, &cerr) || fmt.Sprint(cerr.Rows) != "[2 3]" {
+		t.Errorf("Actual error %v does not list the failed rows.", err)
+	} else if fmt.Sprint(ages) != "[2.5 7 0 0]" {
+		t.Errorf("Actual values %v are not equal to expected.", ages)
+	}
+	nullable, err := GetColumn[*float64](d, "Patient", "Age")
+	if !errors.As(err, &cerr) || fmt.Sprint(cerr.Rows) != "[3]" || nullable[2] != nil || *nullable[1] != 7 {
+		t.Errorf("NULL value was not converted to a nil pointer: %v", err)
+	}
+	if _, err = d.GetColumnNullFloat("Patient", "Age"); !strings.Contains(fmt.Sprint(err), "1 rows failed (3)") {
+		t.Errorf("Actual error %v does not list the failed row.", err)
+	}
+	times, err := d.GetColumnNullTime("Patient", "Admitted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []sql.NullTime{
+		{Time: time.Date(2020, 1, 2, 3, 4, 5, 5e8, time.UTC), Valid: true},
+		{Time: time.Date(2021, 6, 30, 0, 0, 0, 0, time.UTC), Valid: true},
+		{Time: time.Date(2022, 3, 4, 0, 0, 0, 0, time.UTC), Valid: true},
+		{},
+	}
+	if !reflect.DeepEqual(times, expected) {
+		t.Errorf("Actual times %v are not equal to expected: %v", times, expected)
+	}
+	if _, err = d.GetColumnTime("Patient", "Admitted"); !errors.As(err, &cerr) || fmt.Sprint(cerr.Rows) != "[3]" {
+		t.Errorf("NULL value was not reported as a failed conversion: %v", err)
+	}
+	neutered, err := d.GetColumnBool("Patient", "Neutered")
+	if err != nil || fmt.Sprint(neutered) != "[true false true]" {
+		t.Errorf("Actual values %v are not equal to expected (%v).", neutered, err)
+	}
+	if _, err = GetColumn[int](d, "Patient", "Sex`"); !errors.Is(err, ErrUnknownColumn) {
+		t.Errorf("Actual error %v is not equal to expected: %v", err, ErrUnknownColumn)
+	}
+}
+
+func TestGetRange(t *testing.T) {
+	// Tests minimum and maximum values (in extremes.go)
+	d, rec := newFakeDBIO(t)
+	d.Columns["Patient"] = "ID,Sex,Age,Species,Admitted"
+	rec.results["MIN(`Age`),MAX(`Age`)"] = fakeResult{[]string{"MIN", "MAX"}, [][]driver.Value{{[]byte("1.5"), []byte("12.25")}}, nil}
+	rec.results["MIN(`Admitted`),MAX(`Admitted`)"] = fakeResult{[]string{"MIN", "MAX"}, [][]driver.Value{{[]byte("2020-01-02"), []byte("2021-03-04 05:06:07")}}, nil}
+	rec.results["MIN(`ID`),MAX(`ID`)"] = fakeResult{[]string{"MIN", "MAX"}, [][]driver.Value{{nil, nil}}, nil}
+	rec.results["information_schema.TABLES"] = fakeResult{[]string{"AUTO_INCREMENT"}, [][]driver.Value{{int64(42)}}, nil}
+	ages, err := GetRange[float64](d, "Patient", "Age", Filter{"Species", "=", "cat"}, Filter{"Sex", "IN", []string{"F", "M"}})
+	if err != nil || !ages.Valid || ages.Min != 1.5 || ages.Max != 12.25 {
+		t.Errorf("Actual range %v is not equal to expected (%v).", ages, err)
+	}
+	statements := rec.log()
+	expected := "SELECT MIN(`Age`),MAX(`Age`) FROM `Patient` WHERE `Species` = ? AND `Sex` IN (?,?);"
+	if statements[len(statements)-1] != expected {
+		t.Errorf("Actual statement %s is not equal to expected: %s", statements[len(statements)-1], expected)
+	}
+	dates, err := GetRange[time.Time](d, "Patient", "Admitted")
+	if err != nil || !dates.Min.Equal(time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)) || !dates.Max.Equal(time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)) {
+		t.Errorf("Actual range %v is not equal to expected (%v).", dates, err)
+	}
+	text, err := GetRange[string](d, "Patient", "Age")
+	if err != nil || text.Min != "1.5" || text.Max != "12.25" {
+		t.Errorf("Actual range %v is not equal to expected (%v).", text, err)
+	}
+	ids, err := GetRange[int](d, "Patient", "ID")
+	if err != nil || ids.Valid {
+		t.Errorf("Range of an empty table %v is valid (%v).", ids, err)
+	}
+	if m, err := d.GetMaxE("Patient", "ID"); err != nil || m != 0 {
+		t.Errorf("Actual maximum %d of an empty table is not 0 (%v).", m, err)
+	}
+	if _, err = GetRange[int](d, "Patient", "ID", Filter{"Age", "= 1 OR 1 =", 1}); !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("Actual error %v is not equal to expected: %v", err, ErrInvalidQuery)
+	}
+	if n, err := d.NextAutoIncrement("Patient"); err != nil || n != 42 {
+		t.Errorf("Actual auto increment value %d is not equal to 42 (%v).", n, err)
+	}
+	// Both queries are retried after transient errors
+	d.Retry = &RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond}
+	deadlock := &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+	for _, i := range []string{"MIN(", "information_schema.TABLES"} {
+		rec.Lock()
+		rec.fail[i] = deadlock
+		rec.failCount[i] = 1
+		rec.Unlock()
+	}
+	if _, err = GetRange[int](d, "Patient", "ID"); err != nil {
+		t.Errorf("Range query was not retried: %v", err)
+	}
+	if _, err := d.NextAutoIncrement("Patient"); err != nil {
+		t.Errorf("Auto increment query was not retried: %v", err)
+	}
+}
+
+func TestInsertRows(t *testing.T) {
+	// Tests parameterized batch inserts (in batch.go)
+	d, rec := newFakeDBIO(t)
+	// NullValue only affects how NULL is read, so values are stored exactly as given
+	d.NullValue = NullNA
+	values := [][]string{{"1", `C:\data\file_1`, "it's", "NA"}, {"2", "", `"quoted"`, "cat"}}
+	n, err := d.InsertRows("Patient", values)
+	if err != nil || n != 2 {
+		t.Fatalf("Actual number of rows uploaded %d is not equal to 2 (%v).", n, err)
+	}
+	expected := "INSERT INTO `Patient` (`ID`,`Sex`,`Age`,`Species`) VALUES (?,?,?,?),(?,?,?,?);"
+	if s := rec.log(); len(s) != 2 || s[1] != expected {
+		t.Errorf("Actual statements %v are not equal to expected: %s", s, expected)
+	}
+	args := []driver.Value{"1", `C:\data\file_1`, "it's", "NA", "2", "", `"quoted"`, "cat"}
+	if !reflect.DeepEqual(rec.args[1], args) {
+		t.Errorf("Actual arguments %q are not equal to expected: %q", rec.args[1], args)
+	}
+	if _, err = d.Upload("Patient", values, UploadOptions{Null: NullNA}); err != nil {
+		t.Fatal(err)
+	}
+	args[3] = nil
+	if last := rec.args[len(rec.args)-1]; !reflect.DeepEqual(last, args) {
+		t.Errorf("Actual arguments %q with Null set are not equal to expected: %q", last, args)
+	}
+	// Statements are split to stay within the placeholder limit
+	d, rec = newFakeDBIO(t)
+	d.MaxPacket = 64 << 20
+	values = make([][]string, 20000)
+	for i := range values {
+		values[i] = []string{strconv.Itoa(i), "F", "1", "cat"}
+	}
+	if n, err = d.InsertRows("Patient", values); err != nil || n != len(values) {
+		t.Errorf("Actual number of rows uploaded %d is not equal to %d (%v).", n, len(values), err)
+	}
+	for _, i := range rec.args {
+		if len(i) > maxPlaceholders {
+			t.Errorf("Statement with %d arguments exceeds placeholder limit.", len(i))
+		}
+	}
+	if len(rec.args) != 2 || len(rec.args[0]) != 65532 {
+		t.Errorf("Rows were not split into the fewest statements: %d", len(rec.args))
+	}
+	if _, err = d.InsertRows("Patient", [][]string{{"1", "F", "1", "cat"}, {"2", "M"}}); !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("Actual error %v is not equal to expected: %v", err, ErrInvalidQuery)
+	}
+}
+
+func TestMaxPacket(t *testing.T) {
+	// Tests chunk sizing by max_allowed_packet (in batch.go)
+	d, rec := newFakeDBIO(t)
+	rec.results["@@max_allowed_packet"] = fakeResult{[]string{"@@max_allowed_packet"}, [][]driver.Value{{int64(4096)}}, nil}
+	var values [][]string
+	for i := 0; i < 20; i++ {
+		values = append(values, []string{strconv.Itoa(i), "F", "1", strings.Repeat("x", 500)})
+	}
+	for i := 0; i < 2; i++ {
+		if n, err := d.InsertRows("Patient", values); err != nil || n != len(values) {
+			t.Fatalf("Actual number of rows uploaded %d is not equal to %d (%v).", n, len(values), err)
+		}
+	}
+	if n := countStatements(rec.log(), "@@max_allowed_packet"); n != 1 {
+		t.Errorf("max_allowed_packet was read %d times instead of once.", n)
+	}
+	rec.Lock()
+	for _, i := range rec.args[1:] {
+		var size int
+		for _, v := range i {
+			if s, ok := v.(string); ok {
+				size += len(s)
+			}
+		}
+		if size > 4096 {
+			t.Errorf("Chunk of %d bytes exceeds max_allowed_packet.", size)
+		}
+	}
+	rec.Unlock()
+	// Statements which are too large are split in half
+	d, rec = newFakeDBIO(t)
+	d.MaxPacket = 64 << 20
+	rec.fail["big"] = &mysql.MySQLError{Number: 1153, Message: "Got a packet bigger than 'max_allowed_packet' bytes"}
+	rec.failCount["big"] = 2
+	values = [][]string{{"1", "F", "1", "big"}, {"2", "F", "1", "cat"}, {"3", "M", "1", "dog"}, {"4", "M", "1", "cat"}}
+	if n, err := d.InsertRows("Patient", values); err != nil || n != len(values) {
+		t.Errorf("Actual number of rows uploaded %d is not equal to %d (%v).", n, len(values), err)
+	}
+	var rows []int
+	for _, i := range rec.args {
+		rows = append(rows, len(i)/4)
+	}
+	if fmt.Sprint(rows) != "[4 2 1 1 2]" {
+		t.Errorf("Actual rows per statement %v are not equal to expected.", rows)
+	}
+	rec.fail["big"] = &mysql.MySQLError{Number: 1153, Message: "Got a packet bigger than 'max_allowed_packet' bytes"}
+	delete(rec.failCount, "big")
+	if n, err := d.InsertRows("Patient", values); !IsPacketTooLarge(err) || n != 0 {
+		t.Errorf("Actual error %v after %d rows is not a packet too large error.", err, n)
+	}
+}
+
+func TestUploadModes(t *testing.T) {
+	// Tests insert ignore, replace, and upsert statements (in batch.go)
+	d, rec := newFakeDBIO(t)
+	d.MaxPacket = 64 << 20
+	rec.results["information_schema.STATISTICS"] = fakeResult{[]string{"COLUMN_NAME"}, [][]driver.Value{{[]byte("ID")}}, nil}
+	rec.affected["INSERT IGNORE"] = 3
+	rec.affected["REPLACE"] = 6
+	rec.affected["`Sex` = VALUES(`Sex`),`Age`"] = 4
+	rec.affected["`Age` = VALUES(`Age`);"] = 2
+	values := [][]string{{"1", "F", "1", "cat"}, {"2", "M", "3", "dog"}, {"3", "F", "2", "cat"}, {"4", "M", "9", "dog"}}
+	matches := []struct {
+		opts     UploadOptions
+		cmd      string
+		expected ChunkResult
+	}{
+		{UploadOptions{Mode: ModeIgnore}, "INSERT IGNORE INTO `Patient`", ChunkResult{4, 3, 0, 1}},
+		{UploadOptions{Mode: ModeReplace}, "REPLACE INTO `Patient`", ChunkResult{4, 2, 2, 0}},
+		{UploadOptions{Mode: ModeUpsert}, " ON DUPLICATE KEY UPDATE `Sex` = VALUES(`Sex`),`Age` = VALUES(`Age`),`Species` = VALUES(`Species`);", ChunkResult{4, 4, 0, 0}},
+		{UploadOptions{Mode: ModeUpsert, Update: []string{"age"}}, " ON DUPLICATE KEY UPDATE `Age` = VALUES(`Age`);", ChunkResult{4, 2, 0, 2}},
+	}
+	for _, i := range matches {
+		res, err := d.Upload("Patient", values, i.opts)
+		if err != nil {
+			t.Errorf("Unexpected error uploading with %v: %v", i.opts, err)
+			continue
+		}
+		statements := rec.log()
+		if cmd := statements[len(statements)-1]; !strings.Contains(cmd, i.cmd) {
+			t.Errorf("Actual statement %s does not contain expected: %s", cmd, i.cmd)
+		}
+		if len(res.Chunks) != 1 || res.Chunks[0] != i.expected || res.Inserted != i.expected.Inserted || res.Updated != i.expected.Updated {
+			t.Errorf("Actual result %+v is not equal to expected: %+v", res, i.expected)
+		}
+	}
+	if _, err := d.Upload("Patient", values, UploadOptions{Mode: ModeUpsert, Update: []string{"Age`"}}); !errors.Is(err, ErrUnknownColumn) {
+		t.Errorf("Actual error %v is not equal to expected: %v", err, ErrUnknownColumn)
+	}
+	if _, err := d.Upload("Patient", values, UploadOptions{Mode: InsertMode(9)}); !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("Actual error %v is not equal to expected: %v", err, ErrInvalidQuery)
+	}
+}
+
+func TestUploadColumns(t *testing.T) {
+	// Tests uploads to column subsets and header rows (in batch.go)
+	d, rec := newFakeDBIO(t)
+	d.MaxPacket = 64 << 20
+	rec.results["information_schema.COLUMNS"] = fakeResult{[]string{"COLUMN_NAME"}, [][]driver.Value{{[]byte("ID")}, {[]byte("Sex")}}, nil}
+	matches := []struct {
+		rows [][]string
+		opts UploadOptions
+		cmd  string
+		args []driver.Value
+	}{
+		{[][]string{{"cat", "1"}}, UploadOptions{Columns: []string{"species", "ID"}}, "INSERT INTO `Patient` (`Species`,`ID`) VALUES (?,?);", []driver.Value{"cat", "1"}},
+		{[][]string{{"Age", " id"}, {"3", "1"}, {"4", "2"}}, UploadOptions{Header: true}, "INSERT INTO `Patient` (`Age`,`ID`) VALUES (?,?),(?,?);", []driver.Value{"3", "1", "4", "2"}},
+		{[][]string{{"3", "dog"}}, UploadOptions{SkipDefaults: true}, "INSERT INTO `Patient` (`Age`,`Species`) VALUES (?,?);", []driver.Value{"3", "dog"}},
+	}
+	for _, i := range matches {
+		res, err := d.Upload("Patient", i.rows, i.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		statements := rec.log()
+		if cmd := statements[len(statements)-1]; cmd != i.cmd {
+			t.Errorf("Actual statement %s is not equal to expected: %s", cmd, i.cmd)
+		}
+		if args := rec.args[len(rec.args)-1]; !reflect.DeepEqual(args, i.args) {
+			t.Errorf("Actual arguments %q are not equal to expected: %q", args, i.args)
+		}
+		if n := len(i.args) / len(i.rows[0]); res.Rows != n {
+			t.Errorf("Actual number of rows uploaded %d is not equal to %d.", res.Rows, n)
+		}
+	}
+	n := len(rec.log())
+	_, err := d.Upload("Patient", [][]string{{"Age", "ID"}, {"3", "1"}, {"4"}}, UploadOptions{Header: true})
+	var werr *RowWidthError
+	if !errors.As(err, &werr) || !errors.Is(err, ErrInvalidQuery) {
+		t.Fatalf("Actual error %v is not a RowWidthError.", err)
+	} else if werr.Row != 2 || werr.Values != 1 || werr.Columns != 2 {
+		t.Errorf("Actual error %+v does not report the short row.", werr)
+	}
+	if len(rec.log()) != n {
+		t.Error("Rows were uploaded before the width of every row was checked.")
+	}
+	errs := map[*UploadOptions]error{
+		{Columns: []string{"ID", "Breed"}}: ErrUnknownColumn,
+		{Columns: []string{"ID", "id"}}:    ErrInvalidQuery,
+		{Header: true}:                     ErrInvalidQuery,
+	}
+	for k, v := range errs {
+		var rows [][]string
+		if !k.Header {
+			rows = [][]string{{"1", "cat"}}
+		}
+		if _, err = d.Upload("Patient", rows, *k); !errors.Is(err, v) {
+			t.Errorf("Actual error %v is not equal to expected: %v", err, v)
+		}
+	}
+}
+
+func TestBulkLoad(t *testing.T) {
+	// Tests LOAD DATA statements and encoding (in bulk.go)
+	d, rec := newFakeDBIO(t)
+	rec.results["SHOW WARNINGS"] = fakeResult{[]string{"Level", "Code", "Message"}, [][]driver.Value{
+		{[]byte("Warning"), int64(1265), []byte("Data truncated for column 'Age' at row 2")},
+	}, nil}
+	rec.affected["LOAD DATA"] = 3
+	res, err := d.BulkLoad("Patient", strings.NewReader("ID,Species\n1,cat\n"), BulkOptions{Columns: []string{"id", "Species"},
+		FieldTerminator: ",", Enclosure: `"`, IgnoreLines: 1, Mode: ModeReplace})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `LOAD DATA LOCAL INFILE 'Reader::dbio_bulk_1' REPLACE INTO TABLE ` + "`Patient`" + ` CHARACTER SET utf8mb4 FIELDS TERMINATED BY ',' OPTIONALLY ENCLOSED BY '"' ESCAPED BY '\\' LINES TERMINATED BY '\n' IGNORE 1 LINES (` + "`ID`,`Species`);"
+	compareStatements(t, rec.log(), []string{expected, "SHOW WARNINGS"})
+	if res.Rows != 3 || len(res.Warnings) != 1 || res.Warnings[0].Code != 1265 {
+		t.Errorf("Actual result %+v is not equal to expected.", res)
+	}
+	var b strings.Builder
+	values := [][]string{{"1", "a\tb", `C:\dir`, `\N`}, {"2", "line\nbreak", "", "cat"}}
+	if err = writeBulkRows(&b, values, NullSentinel); err != nil {
+		t.Fatal(err)
+	}
+	encoded := "1\ta\\tb\tC:\\\\dir\t\\N\n2\tline\\nbreak\t\tcat\n"
+	if b.String() != encoded {
+		t.Errorf("Actual encoding %q is not equal to expected: %q", b.String(), encoded)
+	}
+	// Values are loaded unchanged if no null value is given
+	b.Reset()
+	if err = writeBulkRows(&b, values[:1], ""); err != nil {
+		t.Fatal(err)
+	}
+	if encoded = "1\ta\\tb\tC:\\\\dir\t\\\\N\n"; b.String() != encoded {
+		t.Errorf("Actual encoding %q is not equal to expected: %q", b.String(), encoded)
+	}
+	if _, err = d.BulkLoadRows("Patient", [][]string{{"1", "F"}}, BulkOptions{}); !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("Actual error %v is not equal to expected: %v", err, ErrInvalidQuery)
+	}
+	if _, err = d.BulkLoad("Patient", strings.NewReader(""), BulkOptions{Mode: ModeUpsert}); !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("Actual error %v is not equal to expected: %v", err, ErrInvalidQuery)
+	}
+	if _, err = d.BulkLoadRows("Patient", values, BulkOptions{}); err != nil {
+		t.Errorf("Unexpected error loading rows: %v", err)
+	}
 }
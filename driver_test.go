@@ -0,0 +1,219 @@
+// Defines a fake sql driver which records statements for testing
+
+package dbIO
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func init() {
+	sql.Register("dbio_fake", fakeDriver{})
+}
+
+// fakeResult stores the columns and rows returned by a query.
+type fakeResult struct {
+	columns []string
+	rows    [][]driver.Value
+	// types stores the database type name of each column.
+	types []string
+}
+
+// fakeRecorder stores statements submitted to a fake connection.
+type fakeRecorder struct {
+	sync.Mutex
+	statements []string
+	args       [][]driver.Value
+	// fail returns an error for any statement or string argument containing the key.
+	fail map[string]error
+	// failCount limits the number of times the error in fail is returned for the key. The error is always returned if the key is not present.
+	failCount map[string]int
+	// results returns rows for any query containing the key.
+	results map[string]fakeResult
+	// affected is the number of affected rows returned for any statement containing the key. It is 1 if no key matches.
+	affected map[string]int64
+}
+
+var recorders = struct {
+	sync.Mutex
+	m map[string]*fakeRecorder
+}{m: make(map[string]*fakeRecorder)}
+
+// Returns true if any string argument contains key.
+func argsContain(args []driver.Value, key string) bool {
+	for _, i := range args {
+		if v, ok := i.(string); ok && strings.Contains(v, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// Records statement and returns any error registered for it.
+func (r *fakeRecorder) add(query string, args []driver.Value) error {
+	r.Lock()
+	defer r.Unlock()
+	r.statements = append(r.statements, query)
+	r.args = append(r.args, args)
+	for k, v := range r.fail {
+		if strings.Contains(query, k) || argsContain(args, k) {
+			if n, ex := r.failCount[k]; ex {
+				if n <= 0 {
+					continue
+				}
+				r.failCount[k] = n - 1
+			}
+			return v
+		}
+	}
+	return nil
+}
+
+// Returns a copy of the recorded statements.
+func (r *fakeRecorder) log() []string {
+	r.Lock()
+	defer r.Unlock()
+	return append([]string(nil), r.statements...)
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	recorders.Lock()
+	defer recorders.Unlock()
+	return &fakeConn{rec: recorders.m[name]}, nil
+}
+
+type fakeConn struct {
+	rec *fakeRecorder
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{conn: c, query: query}, nil
+}
+
+func (c *fakeConn) Ping(ctx context.Context) error {
+	return c.rec.add("PING", nil)
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if err := c.rec.add("BEGIN", nil); err != nil {
+		return nil, err
+	}
+	return &fakeTx{conn: c}, nil
+}
+
+type fakeTx struct {
+	conn *fakeConn
+}
+
+func (t *fakeTx) Commit() error {
+	return t.conn.rec.add("COMMIT", nil)
+}
+
+func (t *fakeTx) Rollback() error {
+	return t.conn.rec.add("ROLLBACK", nil)
+}
+
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if err := s.conn.rec.add(s.query, args); err != nil {
+		return nil, err
+	}
+	s.conn.rec.Lock()
+	defer s.conn.rec.Unlock()
+	for k, v := range s.conn.rec.affected {
+		if strings.Contains(s.query, k) {
+			return driver.RowsAffected(v), nil
+		}
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if err := s.conn.rec.add(s.query, args); err != nil {
+		return nil, err
+	}
+	s.conn.rec.Lock()
+	defer s.conn.rec.Unlock()
+	for k, v := range s.conn.rec.results {
+		if strings.Contains(s.query, k) {
+			return &fakeRows{result: v}, nil
+		}
+	}
+	return &fakeRows{}, nil
+}
+
+type fakeRows struct {
+	result fakeResult
+	idx    int
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.result.columns
+}
+
+func (r *fakeRows) ColumnTypeDatabaseTypeName(index int) string {
+	if index < len(r.result.types) {
+		return r.result.types[index]
+	}
+	return ""
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.idx >= len(r.result.rows) {
+		return io.EOF
+	}
+	copy(dest, r.result.rows[r.idx])
+	r.idx++
+	return nil
+}
+
+// Returns DBIO struct connected to the fake driver, and the recorder for its statements.
+func newFakeDBIO(t *testing.T) (*DBIO, *fakeRecorder) {
+	rec := &fakeRecorder{fail: make(map[string]error), failCount: make(map[string]int), results: make(map[string]fakeResult), affected: make(map[string]int64)}
+	recorders.Lock()
+	recorders.m[t.Name()] = rec
+	recorders.Unlock()
+	d := getTestDBIO()
+	db, err := sql.Open("dbio_fake", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Use a single connection so statements are recorded in order
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() {
+		db.Close()
+	})
+	d.DB = db
+	return d, rec
+}
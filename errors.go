@@ -0,0 +1,213 @@
+// Defines sentinel errors returned by dbIO
+
+package dbIO
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+)
+
+var (
+	// ErrUnknownTable is returned when a table is not present in DBIO.Columns.
+	ErrUnknownTable = errors.New("dbIO: unknown table")
+	// ErrUnknownColumn is returned when a column is not present in the given table.
+	ErrUnknownColumn = errors.New("dbIO: unknown column")
+	// ErrInvalidQuery is returned when the arguments given to a query are incomplete or unsupported (such as an unknown operator).
+	ErrInvalidQuery = errors.New("dbIO: invalid query")
+	// ErrConnection is returned when a connection to the MySQL server cannot be established.
+	ErrConnection = errors.New("dbIO: connection failed")
+	// ErrSchemaParse is returned when a schema file cannot be parsed or its statements cannot be executed.
+	ErrSchemaParse = errors.New("dbIO: schema parse failed")
+	// ErrCredentials is returned when credentials cannot be read or are missing and prompting is disabled.
+	ErrCredentials = errors.New("dbIO: missing credentials")
+)
+
+// Logs the given error, if any.
+func (d *DBIO) logError(err error) {
+	if err != nil {
+		d.logger.Printf("[Error] %v\n", err)
+	}
+}
+
+// ErrorKind classifies errors returned by the MySQL server.
+type ErrorKind int
+
+const (
+	// KindOther is any error which does not fall into one of the categories below.
+	KindOther ErrorKind = iota
+	// KindDuplicateKey is returned when a row would duplicate a unique or primary key.
+	KindDuplicateKey
+	// KindForeignKey is returned when a row would violate a foreign key constraint.
+	KindForeignKey
+	// KindLockWaitTimeout is returned when a lock could not be acquired before innodb_lock_wait_timeout.
+	KindLockWaitTimeout
+	// KindDeadlock is returned when the server rolled back a transaction to resolve a deadlock.
+	KindDeadlock
+	// KindPacketTooLarge is returned when a statement exceeds max_allowed_packet.
+	KindPacketTooLarge
+	// KindUnknownColumn is returned when a statement references a column which does not exist.
+	KindUnknownColumn
+	// KindAccessDenied is returned when the user lacks the privileges required by a statement.
+	KindAccessDenied
+	// KindBadConnection is returned when the connection to the server was lost.
+	KindBadConnection
+)
+
+// String returns a short description of the error kind.
+func (k ErrorKind) String() string {
+	switch k {
+	case KindDuplicateKey:
+		return "duplicate key"
+	case KindForeignKey:
+		return "foreign key violation"
+	case KindLockWaitTimeout:
+		return "lock wait timeout"
+	case KindDeadlock:
+		return "deadlock"
+	case KindPacketTooLarge:
+		return "packet too large"
+	case KindUnknownColumn:
+		return "unknown column"
+	case KindAccessDenied:
+		return "access denied"
+	case KindBadConnection:
+		return "bad connection"
+	}
+	return "other"
+}
+
+// MySQL server error numbers for each kind of error.
+var errorNumbers = map[uint16]ErrorKind{
+	1022: KindDuplicateKey,
+	1062: KindDuplicateKey,
+	1586: KindDuplicateKey,
+	1216: KindForeignKey,
+	1217: KindForeignKey,
+	1451: KindForeignKey,
+	1452: KindForeignKey,
+	1205: KindLockWaitTimeout,
+	1213: KindDeadlock,
+	1153: KindPacketTooLarge,
+	1301: KindPacketTooLarge,
+	1054: KindUnknownColumn,
+	1044: KindAccessDenied,
+	1045: KindAccessDenied,
+	1142: KindAccessDenied,
+	1143: KindAccessDenied,
+	1227: KindAccessDenied,
+}
+
+// Error wraps an error returned by the MySQL driver with the operation and table which produced it.
+type Error struct {
+	// Kind is the classification of the underlying error.
+	Kind ErrorKind
+	// Op describes the operation which failed (e.g. "uploading to").
+	Op string
+	// Table is the name of the target table.
+	Table string
+	// Err is the error returned by the driver.
+	Err error
+}
+
+// Error returns the operation, table, and underlying error message.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s %s: %v", e.Op, e.Table, e.Err)
+}
+
+// Unwrap returns the underlying driver error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Number returns the MySQL server error number, or 0 if the error did not originate from the server.
+func (e *Error) Number() uint16 {
+	var merr *mysql.MySQLError
+	if errors.As(e.Err, &merr) {
+		return merr.Number
+	}
+	return 0
+}
+
+// Returns the kind of a driver error.
+func classify(err error) ErrorKind {
+	var merr *mysql.MySQLError
+	if errors.As(err, &merr) {
+		if k, ex := errorNumbers[merr.Number]; ex {
+			return k
+		}
+		return KindOther
+	}
+	switch {
+	case errors.Is(err, mysql.ErrPktTooLarge):
+		return KindPacketTooLarge
+	case errors.Is(err, mysql.ErrInvalidConn), errors.Is(err, driver.ErrBadConn):
+		return KindBadConnection
+	}
+	return KindOther
+}
+
+// Wraps err in an Error for the given operation and table. Returns nil if err is nil.
+func newError(op, table string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Kind: classify(err), Op: op, Table: table, Err: err}
+}
+
+// Kind returns the kind of err. Errors which were not returned by dbIO are classified directly.
+func Kind(err error) ErrorKind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return classify(err)
+}
+
+// IsDuplicateKey returns true if err was caused by a duplicate unique or primary key.
+func IsDuplicateKey(err error) bool {
+	return err != nil && Kind(err) == KindDuplicateKey
+}
+
+// IsForeignKeyViolation returns true if err was caused by a foreign key constraint.
+func IsForeignKeyViolation(err error) bool {
+	return err != nil && Kind(err) == KindForeignKey
+}
+
+// IsLockWaitTimeout returns true if err was caused by a lock wait timeout.
+func IsLockWaitTimeout(err error) bool {
+	return err != nil && Kind(err) == KindLockWaitTimeout
+}
+
+// IsDeadlock returns true if err was caused by a deadlock.
+func IsDeadlock(err error) bool {
+	return err != nil && Kind(err) == KindDeadlock
+}
+
+// IsPacketTooLarge returns true if err was caused by a statement exceeding max_allowed_packet.
+func IsPacketTooLarge(err error) bool {
+	return err != nil && Kind(err) == KindPacketTooLarge
+}
+
+// IsUnknownColumn returns true if err was caused by a reference to a missing column.
+func IsUnknownColumn(err error) bool {
+	return err != nil && Kind(err) == KindUnknownColumn
+}
+
+// IsAccessDenied returns true if err was caused by insufficient privileges.
+func IsAccessDenied(err error) bool {
+	return err != nil && Kind(err) == KindAccessDenied
+}
+
+// IsRetryable returns true if the failed statement may succeed if it is submitted again (i.e. deadlocks, lock wait timeouts, and lost connections).
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	switch Kind(err) {
+	case KindDeadlock, KindLockWaitTimeout, KindBadConnection:
+		return true
+	}
+	return false
+}
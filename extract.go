@@ -3,224 +3,332 @@
 package dbIO
 
 import (
-	"bytes"
+	"context"
 	"database/sql"
 	"fmt"
 	"strings"
 )
 
 // Returns integer from query
-func (d *DBIO) getCount(table, cmd string) int {
+func (d *DBIO) getCount(ctx context.Context, table, cmd string, args ...interface{}) (int, error) {
 	var n int
-	val := d.DB.QueryRow(cmd)
-	err := val.Scan(&n)
+	val := d.conn().QueryRowContext(ctx, cmd, args...)
+	if err := val.Scan(&n); err != nil {
+		return n, fmt.Errorf("counting entries from %s: %w", table, err)
+	}
+	return n, nil
+}
+
+// CountCtx returns count of entries from target column(s) in table where key relates to column via op (>=/=/...; ie. column >= 7).
+// Returns total if distinct is false; returns number of unique entries if distinct is true (target must name the columns).
+// Give column, operator, and key as emtpy strings to count without evaluating.
+func (d *DBIO) CountCtx(ctx context.Context, table, column, target, op, key string, distinct bool) (int, error) {
+	cmd, args, err := d.countQuery(ctx, table, column, target, op, key, distinct)
 	if err != nil {
-		d.logger.Printf("[Error] Counting entries from %s: %v\n\n", table, err)
+		return -1, fmt.Errorf("counting entries from %s: %w", table, err)
 	}
-	return n
+	return d.getCount(ctx, table, cmd, args...)
+}
+
+// CountE returns count of entries from target column(s) in table where key relates to column via op (>=/=/...; ie. column >= 7).
+// Returns total if distinct is false; returns number of unique entries if distinct is true (target must name the columns).
+// Give column, operator, and key as emtpy strings to count without evaluating.
+func (d *DBIO) CountE(table, column, target, op, key string, distinct bool) (int, error) {
+	return d.CountCtx(context.Background(), table, column, target, op, key, distinct)
 }
 
 // Count returns count of entries from target column(s) in table where key relates to column via op (>=/=/...; ie. column >= 7).
-// Returns total if distinct is false; returns number of unique entries if distinct is true.
-// Give operator, key, and target as emtpy strings to count without evaluating.
+// Returns total if distinct is false; returns number of unique entries if distinct is true (target must name the columns).
+// Give column, operator, and key as emtpy strings to count without evaluating. Returns -1 if the query cannot be formatted.
+//
+// Deprecated: Use CountE, which returns errors instead of logging them.
 func (d *DBIO) Count(table, column, target, op, key string, distinct bool) int {
-	var cmd string
-	if distinct == true {
-		cmd = fmt.Sprintf("SELECT COUNT(DISTINCT %s) FROM %s", target, table)
-	} else {
-		cmd = fmt.Sprintf("SELECT COUNT(%s) FROM %s", target, table)
-	}
-	if len(op) >= 1 || len(key) >= 1 || len(column) >= 1 {
-		if len(op) >= 1 && len(key) >= 1 && len(column) >= 1 {
-			// Add evaluation statement
-			cmd += fmt.Sprintf(" WHERE %s %s '%s'", column, op, key)
-		} else {
-			fmt.Print("\n\t[Error] Please specify target column, operator, and target value. Returning -1.\n")
-			return -1
-		}
+	n, err := d.CountE(table, column, target, op, key, distinct)
+	d.logError(err)
+	return n
+}
+
+// CountRowsCtx returns the number of rows from the given table.
+func (d *DBIO) CountRowsCtx(ctx context.Context, table string) (int, error) {
+	tbl, err := d.checkTable(ctx, table)
+	if err != nil {
+		return 0, fmt.Errorf("counting rows from %s: %w", table, err)
 	}
-	return d.getCount(table, cmd)
+	return d.getCount(ctx, table, fmt.Sprintf("SELECT COUNT(*) FROM %s;", tbl))
+}
+
+// CountRowsE returns the number of rows from the given table.
+func (d *DBIO) CountRowsE(table string) (int, error) {
+	return d.CountRowsCtx(context.Background(), table)
 }
 
 // CountRows returns the number of rows from the given table.
+//
+// Deprecated: Use CountRowsE, which returns errors instead of logging them.
 func (d *DBIO) CountRows(table string) int {
-	cmd := fmt.Sprintf("SELECT COUNT(*) FROM %s;", table)
-	return d.getCount(table, cmd)
+	n, err := d.CountRowsE(table)
+	d.logError(err)
+	return n
+}
+
+// GetMaxCtx returns the highest number from the given column. Returns 0 if the table is empty.
+func (d *DBIO) GetMaxCtx(ctx context.Context, table, column string) (int, error) {
+	r, err := GetRangeCtx[int](ctx, d, table, column)
+	return r.Max, err
+}
+
+// GetMaxE returns the highest number from the given column.
+func (d *DBIO) GetMaxE(table, column string) (int, error) {
+	return d.GetMaxCtx(context.Background(), table, column)
 }
 
 // GetMax returns the highest number from the given column.
+//
+// Deprecated: Use GetMaxE, which returns errors instead of logging them.
 func (d *DBIO) GetMax(table, column string) int {
-	var m int
-	n := d.CountRows(table)
-	if n > 0 {
-		cmd := fmt.Sprintf("SELECT MAX(%s) FROM %s;", column, table)
-		val := d.DB.QueryRow(cmd)
-		err := val.Scan(&m)
-		if err != nil {
-			d.logger.Printf("[Error] Determining maximum value from %s in %s: %v\n\n", column, table, err)
-		}
-	} else {
-		m = n
-	}
+	m, err := d.GetMaxE(table, column)
+	d.logError(err)
 	return m
 }
 
-// Returns rows of uncertain length as slice of string slices
-func toSlice(rows *sql.Rows) [][]string {
-	var ret [][]string
-	columns, _ := rows.Columns()
-	count := len(columns)
-	values := make([]interface{}, count)
-	pointers := make([]interface{}, count)
-	for rows.Next() {
-		var r []string
-		for i := range columns {
-			pointers[i] = &values[i]
-		}
-		// Maps items to values via pointers
-		rows.Scan(pointers...)
-		for _, i := range values {
-			// Use Sprintf to convert interface to string
-			val := fmt.Sprintf("%s", i)
-			r = append(r, val)
-		}
-		ret = append(ret, r)
+// ExecuteCtx submits the given command as a MySQL query. Any arguments will be bound to ? placeholders in cmd.
+func (d *DBIO) ExecuteCtx(ctx context.Context, cmd string, args ...interface{}) ([][]string, error) {
+	ret, err := d.queryResult(ctx, cmd, args)
+	if ret == nil {
+		return nil, err
 	}
+	return ret.Rows, err
+}
+
+// ExecuteE submits the given command as a MySQL query. Any arguments will be bound to ? placeholders in cmd.
+func (d *DBIO) ExecuteE(cmd string, args ...interface{}) ([][]string, error) {
+	return d.ExecuteCtx(context.Background(), cmd, args...)
+}
+
+// Execute submits the given command as a MySQL query. Any arguments will be bound to ? placeholders in cmd.
+//
+// Deprecated: Use ExecuteE, which returns errors instead of logging them.
+func (d *DBIO) Execute(cmd string, args ...interface{}) [][]string {
+	ret, err := d.ExecuteE(cmd, args...)
+	d.logError(err)
 	return ret
 }
 
-// Execute submits the given command as a MySQL query.
-func (d *DBIO) Execute(cmd string) [][]string {
-	rows, err := d.DB.Query(cmd)
+// Returns query formatting errors or executes the query.
+func (d *DBIO) executeQuery(ctx context.Context, table, cmd string, args []interface{}, err error) ([][]string, error) {
 	if err != nil {
-		d.logger.Printf("[Error] Executing '%s': %v", cmd, err)
+		return nil, fmt.Errorf("formatting query for %s: %w", table, err)
 	}
-	defer rows.Close()
-	return toSlice(rows)
+	return d.ExecuteCtx(ctx, cmd, args...)
+}
+
+// GetRowsMinCtx returns all rows of target columns where column >= key.
+func (d *DBIO) GetRowsMinCtx(ctx context.Context, table, column, target string, min int) ([][]string, error) {
+	cmd, args, err := d.evaluateQuery(ctx, table, column, ">=", min, target)
+	return d.executeQuery(ctx, table, cmd, args, err)
+}
+
+// GetRowsMinE returns all rows of target columns where column >= key.
+func (d *DBIO) GetRowsMinE(table, column, target string, min int) ([][]string, error) {
+	return d.GetRowsMinCtx(context.Background(), table, column, target, min)
 }
 
 // GetRowsMin returns all rows of target columns where column >= key.
+//
+// Deprecated: Use GetRowsMinE, which returns errors instead of logging them.
 func (d *DBIO) GetRowsMin(table, column, target string, min int) [][]string {
-	var cmd string
-	cmd = fmt.Sprintf("SELECT %s FROM %s WHERE %s >= %d;", target, table, column, min)
-	return d.Execute(cmd)
-}
-
-// Wraps terms in apostrophes to avoid errors
-func addApostrophes(key string) string {
-	s := strings.Split(key, ",")
-	buffer := bytes.NewBufferString("")
-	for idx, i := range s {
-		if idx != 0 {
-			// Write preceding comma
-			buffer.WriteByte(',')
-		}
-		buffer.WriteByte('\'')
-		buffer.WriteString(i)
-		buffer.WriteByte('\'')
-	}
-	return buffer.String()
+	ret, err := d.GetRowsMinE(table, column, target, min)
+	d.logError(err)
+	return ret
+}
+
+// GetRowsCtx returns rows of target columns with key in column. Key may be a comma-seperated list of values.
+func (d *DBIO) GetRowsCtx(ctx context.Context, table, column, key, target string) ([][]string, error) {
+	cmd, args, err := d.getRowsQuery(ctx, table, column, key, target)
+	return d.executeQuery(ctx, table, cmd, args, err)
+}
+
+// GetRowsE returns rows of target columns with key in column. Key may be a comma-seperated list of values.
+func (d *DBIO) GetRowsE(table, column, key, target string) ([][]string, error) {
+	return d.GetRowsCtx(context.Background(), table, column, key, target)
 }
 
-// GetRows returns rows of target columns with key in column.
+// GetRows returns rows of target columns with key in column. Key may be a comma-seperated list of values.
+//
+// Deprecated: Use GetRowsE, which returns errors instead of logging them.
 func (d *DBIO) GetRows(table, column, key, target string) [][]string {
-	var cmd string
-	if strings.Contains(key, ",") == true {
-		// Format for list
-		if strings.Contains(key, "'") == false {
-			key = addApostrophes(key)
-		}
-		cmd = fmt.Sprintf("SELECT %s FROM %s WHERE %s IN (%s);", target, table, column, key)
-	} else {
-		cmd = fmt.Sprintf("SELECT %s FROM %s WHERE %s = '%s';", target, table, column, key)
-	}
-	return d.Execute(cmd)
+	ret, err := d.GetRowsE(table, column, key, target)
+	d.logError(err)
+	return ret
+}
+
+// EvaluateRowsCtx returns rows of columns where key relates to target via op (>=/=/...) (i.e. column <= key).
+func (d *DBIO) EvaluateRowsCtx(ctx context.Context, table, column, op, key, target string) ([][]string, error) {
+	cmd, args, err := d.evaluateQuery(ctx, table, column, op, key, target)
+	return d.executeQuery(ctx, table, cmd, args, err)
+}
+
+// EvaluateRowsE returns rows of columns where key relates to target via op (>=/=/...) (i.e. column <= key).
+func (d *DBIO) EvaluateRowsE(table, column, op, key, target string) ([][]string, error) {
+	return d.EvaluateRowsCtx(context.Background(), table, column, op, key, target)
 }
 
 // EvaluateRows returns rows of columns where key relates to target via op (>=/=/...) (i.e. column <= key).
+//
+// Deprecated: Use EvaluateRowsE, which returns errors instead of logging them.
 func (d *DBIO) EvaluateRows(table, column, op, key, target string) [][]string {
-	cmd := fmt.Sprintf("SELECT %s FROM %s WHERE %s %s '%s';", target, table, column, op, key)
-	return d.Execute(cmd)
+	ret, err := d.EvaluateRowsE(table, column, op, key, target)
+	d.logError(err)
+	return ret
+}
+
+// ColumnContainsCtx returns a 2D string slice from table if value is in column.
+func (d *DBIO) ColumnContainsCtx(ctx context.Context, table, column, value, target string) ([][]string, error) {
+	cmd, args, err := d.containsQuery(ctx, table, column, value, target)
+	return d.executeQuery(ctx, table, cmd, args, err)
+}
+
+// ColumnContainsE returns a 2D string slice from table if value is in column.
+func (d *DBIO) ColumnContainsE(table, column, value, target string) ([][]string, error) {
+	return d.ColumnContainsCtx(context.Background(), table, column, value, target)
 }
 
 // ColumnContains returns a 2D string slice from table if value is in column.
+//
+// Deprecated: Use ColumnContainsE, which returns errors instead of logging them.
 func (d *DBIO) ColumnContains(table, column, value, target string) [][]string {
-	cmd := fmt.Sprintf("SELECT %s FROM %s WHERE INSTR(%s.%s, '%s') > 0;", target, table, table, column, value)
-	return d.Execute(cmd)
+	ret, err := d.ColumnContainsE(table, column, value, target)
+	d.logError(err)
+	return ret
 }
 
-// GetColumnInt returns a slice of all entries in column of integers.
-func (d *DBIO) GetColumnInt(table, column string) []int {
-	var col []int
-	sql := fmt.Sprintf("SELECT %s FROM %s;", column, table)
-	rows, err := d.DB.Query(sql)
+// Returns rows from the given column.
+func (d *DBIO) queryColumn(ctx context.Context, table, column string) (*sql.Rows, error) {
+	cmd, err := d.selectQuery(ctx, table, column, "")
 	if err != nil {
-		d.logger.Printf("[Error] Extracting %s column from %s: %v", column, table, err)
-	}
-	defer rows.Close()
-	for rows.Next() {
-		var val int
-		// Assign data to val while checking err
-		if err := rows.Scan(&val); err != nil {
-			d.logger.Printf("[Error] Reading %s from %s: %v", column, table, err)
-		}
-		col = append(col, val)
+		return nil, err
 	}
+	return d.conn().QueryContext(ctx, cmd)
+}
+
+// GetColumnIntCtx returns a slice of all entries in column of integers.
+func (d *DBIO) GetColumnIntCtx(ctx context.Context, table, column string) ([]int, error) {
+	return GetColumnCtx[int](ctx, d, table, column)
+}
+
+// GetColumnIntE returns a slice of all entries in column of integers.
+func (d *DBIO) GetColumnIntE(table, column string) ([]int, error) {
+	return d.GetColumnIntCtx(context.Background(), table, column)
+}
+
+// GetColumnInt returns a slice of all entries in column of integers.
+//
+// Deprecated: Use GetColumnIntE, which returns errors instead of logging them.
+func (d *DBIO) GetColumnInt(table, column string) []int {
+	col, err := d.GetColumnIntE(table, column)
+	d.logError(err)
 	return col
 }
 
+// GetColumnTextCtx returns a slice of all entries in column of text.
+func (d *DBIO) GetColumnTextCtx(ctx context.Context, table, column string) ([]string, error) {
+	return GetColumnCtx[string](ctx, d, table, column)
+}
+
+// GetColumnTextE returns a slice of all entries in column of text.
+func (d *DBIO) GetColumnTextE(table, column string) ([]string, error) {
+	return d.GetColumnTextCtx(context.Background(), table, column)
+}
+
 // GetColumnText returns a slice of all entries in column of text.
+//
+// Deprecated: Use GetColumnTextE, which returns errors instead of logging them.
 func (d *DBIO) GetColumnText(table, column string) []string {
-	var col []string
-	sql := fmt.Sprintf("SELECT %s FROM %s;", column, table)
-	rows, err := d.DB.Query(sql)
-	if err != nil {
-		d.logger.Printf("[Error] Extracting %s column from %s: %v", column, table, err)
-	}
-	defer rows.Close()
-	for rows.Next() {
-		var val string
-		// Assign data to val while checking err
-		if err := rows.Scan(&val); err != nil {
-			d.logger.Printf("[Error] Reading %s from %s: %v", column, table, err)
-		}
-		col = append(col, val)
-	}
+	col, err := d.GetColumnTextE(table, column)
+	d.logError(err)
 	return col
 }
 
+// GetColumnsCtx returns a slice of slices of all entries in given columns.
+func (d *DBIO) GetColumnsCtx(ctx context.Context, table string, columns []string) ([][]string, error) {
+	cmd, err := d.selectQuery(ctx, table, strings.Join(columns, ","), "")
+	return d.executeQuery(ctx, table, cmd, nil, err)
+}
+
+// GetColumnsE returns a slice of slices of all entries in given columns.
+func (d *DBIO) GetColumnsE(table string, columns []string) ([][]string, error) {
+	return d.GetColumnsCtx(context.Background(), table, columns)
+}
+
 // GetColumns returns a slice of slices of all entries in given columns.
+//
+// Deprecated: Use GetColumnsE, which returns errors instead of logging them.
 func (d *DBIO) GetColumns(table string, columns []string) [][]string {
-	cmd := fmt.Sprintf("SELECT %s FROM %s;", strings.Join(columns, ","), table)
-	return d.Execute(cmd)
+	ret, err := d.GetColumnsE(table, columns)
+	d.logError(err)
+	return ret
+}
+
+// GetNumOccurancesCtx returns a map with the number of unique entries in column.
+func (d *DBIO) GetNumOccurancesCtx(ctx context.Context, table, column string) (map[string]int, error) {
+	// Values are counted by the server so the column is not transferred
+	return d.GroupCountCtx(ctx, table, column)
+}
+
+// GetNumOccurancesE returns a map with the number of unique entries in column.
+func (d *DBIO) GetNumOccurancesE(table, column string) (map[string]int, error) {
+	return d.GetNumOccurancesCtx(context.Background(), table, column)
 }
 
 // GetNumOccurances returns a map with the number of unique entries in column.
+//
+// Deprecated: Use GetNumOccurancesE, which returns errors instead of logging them.
 func (d *DBIO) GetNumOccurances(table, column string) map[string]int {
-	occ := make(map[string]int)
-	entries := d.GetColumnText(table, column)
-	for _, i := range entries {
-		if _, ex := occ[i]; ex == true {
-			occ[i]++
-		} else {
-			occ[i] = 1
-		}
-	}
+	occ, err := d.GetNumOccurancesE(table, column)
+	d.logError(err)
 	return occ
 }
 
+// GetTableCtx returns all contents of the given table.
+func (d *DBIO) GetTableCtx(ctx context.Context, table string) ([][]string, error) {
+	cmd, err := d.selectQuery(ctx, table, "*", "")
+	return d.executeQuery(ctx, table, cmd, nil, err)
+}
+
+// GetTableE returns all contents of the given table.
+func (d *DBIO) GetTableE(table string) ([][]string, error) {
+	return d.GetTableCtx(context.Background(), table)
+}
+
 // GetTable returns all contents of the given table.
+//
+// Deprecated: Use GetTableE, which returns errors instead of logging them.
 func (d *DBIO) GetTable(table string) [][]string {
-	cmd := fmt.Sprintf("SELECT * FROM %s ;", table)
-	return d.Execute(cmd)
+	ret, err := d.GetTableE(table)
+	d.logError(err)
+	return ret
 }
 
-// GetTableMap returns the given table as a map with the first column as the key.
-func (d *DBIO) GetTableMap(table string) map[string][]string {
+// GetTableMapCtx returns the given table as a map with the first column as the key.
+func (d *DBIO) GetTableMapCtx(ctx context.Context, table string) (map[string][]string, error) {
 	tbl := make(map[string][]string)
-	s := d.GetTable(table)
+	s, err := d.GetTableCtx(ctx, table)
 	for _, i := range s {
 		tbl[i[0]] = i[1:]
 	}
+	return tbl, err
+}
+
+// GetTableMapE returns the given table as a map with the first column as the key.
+func (d *DBIO) GetTableMapE(table string) (map[string][]string, error) {
+	return d.GetTableMapCtx(context.Background(), table)
+}
+
+// GetTableMap returns the given table as a map with the first column as the key.
+//
+// Deprecated: Use GetTableMapE, which returns errors instead of logging them.
+func (d *DBIO) GetTableMap(table string) map[string][]string {
+	tbl, err := d.GetTableMapE(table)
+	d.logError(err)
 	return tbl
 }
@@ -0,0 +1,89 @@
+// Contains functions for reading the minimum and maximum values of columns
+
+package dbIO
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Range stores the minimum and maximum values of a column.
+type Range[T any] struct {
+	Min T
+	Max T
+	// Valid is false if there are no non-NULL values (e.g. the table is empty or no rows match the filters).
+	Valid bool
+}
+
+// GetRangeCtx returns the minimum and maximum values of column in a single query. T may be any type accepted by GetColumn (e.g.
+// int64, float64, string for DECIMAL or text columns, or time.Time). Only rows matching all of the given filters are included.
+func GetRangeCtx[T any](ctx context.Context, d *DBIO, table, column string, filters ...Filter) (Range[T], error) {
+	var ret Range[T]
+	s := d.Select().From(table)
+	for _, i := range filters {
+		s.And(i.Column, i.Op, i.Value)
+	}
+	col, err := s.column(ctx, column)
+	if err != nil {
+		return ret, fmt.Errorf("determining range of %s in %s: %w", column, table, err)
+	}
+	where, args, err := s.whereClause(ctx)
+	if err != nil {
+		return ret, fmt.Errorf("determining range of %s in %s: %w", column, table, err)
+	}
+	cmd := fmt.Sprintf("SELECT MIN(%s),MAX(%s) FROM %s%s;", col, col, quoteIdentifier(table), where)
+	err = d.retry(ctx, func() error {
+		ret = Range[T]{}
+		rows, err := d.conn().QueryContext(ctx, cmd, args...)
+		if err != nil {
+			return fmt.Errorf("determining range of %s in %s: %w", column, table, err)
+		}
+		defer rows.Close()
+		if !rows.Next() {
+			return rows.Err()
+		}
+		// MIN and MAX are both NULL if there are no values
+		var min, max *T
+		if err = d.scanValues(rows, &min, &max); err != nil {
+			return fmt.Errorf("reading range of %s in %s: %w", column, table, err)
+		} else if min != nil && max != nil {
+			ret.Min, ret.Max, ret.Valid = *min, *max, true
+		}
+		return rows.Err()
+	})
+	return ret, err
+}
+
+// GetRange returns the minimum and maximum values of column in a single query.
+func GetRange[T any](d *DBIO, table, column string, filters ...Filter) (Range[T], error) {
+	return GetRangeCtx[T](context.Background(), d, table, column, filters...)
+}
+
+// NextAutoIncrementCtx returns the value which will be assigned to the AUTO_INCREMENT column of table by the next insert. Note that
+// MySQL 8.0 may cache this value for up to information_schema_stats_expiry seconds.
+func (d *DBIO) NextAutoIncrementCtx(ctx context.Context, table string) (int64, error) {
+	var n sql.NullInt64
+	if _, err := d.checkTable(ctx, table); err != nil {
+		return 0, fmt.Errorf("reading next auto increment value of %s: %w", table, err)
+	}
+	cmd := "SELECT AUTO_INCREMENT FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?;"
+	err := d.retry(ctx, func() error {
+		return d.conn().QueryRowContext(ctx, cmd, table).Scan(&n)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		err = fmt.Errorf("%w %q", ErrUnknownTable, table)
+	} else if err == nil && !n.Valid {
+		err = fmt.Errorf("%w: %s has no AUTO_INCREMENT column", ErrInvalidQuery, table)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("reading next auto increment value of %s: %w", table, err)
+	}
+	return n.Int64, nil
+}
+
+// NextAutoIncrement returns the value which will be assigned to the AUTO_INCREMENT column of table by the next insert.
+func (d *DBIO) NextAutoIncrement(table string) (int64, error) {
+	return d.NextAutoIncrementCtx(context.Background(), table)
+}
@@ -0,0 +1,51 @@
+//go:build go1.23
+
+// Contains range-over-func iterators for streaming query results
+
+package dbIO
+
+import (
+	"context"
+	"iter"
+)
+
+// All returns an iterator over the remaining rows for use with range. The iterator is closed when the loop ends, including when
+// it ends early. If reading a row fails, the error is yielded with a nil row and iteration stops.
+func (it *RowIterator) All() iter.Seq2[[]string, error] {
+	return func(yield func([]string, error) bool) {
+		defer it.Close()
+		for it.Next() {
+			if !yield(it.Row(), nil) {
+				return
+			}
+		}
+		if err := it.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// TableRowsCtx returns an iterator over the target columns of table for use with range. Use "*" to select entire rows. Rows are
+// read one at a time, and the query is closed when the loop ends.
+//
+//	for row, err := range d.TableRowsCtx(ctx, "Patient", "*") {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+func (d *DBIO) TableRowsCtx(ctx context.Context, table, target string) iter.Seq2[[]string, error] {
+	return func(yield func([]string, error) bool) {
+		it, err := d.StreamTableCtx(ctx, table, target)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		it.All()(yield)
+	}
+}
+
+// TableRows returns an iterator over the target columns of table for use with range.
+func (d *DBIO) TableRows(table, target string) iter.Seq2[[]string, error] {
+	return d.TableRowsCtx(context.Background(), table, target)
+}
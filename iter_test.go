@@ -0,0 +1,36 @@
+//go:build go1.23
+
+package dbIO
+
+import (
+	"database/sql/driver"
+	"testing"
+)
+
+func TestTableRows(t *testing.T) {
+	// Tests range-over-func iterators (in iter.go)
+	d, rec := newFakeDBIO(t)
+	rec.results["Patient"] = fakeResult{[]string{"ID"}, [][]driver.Value{{int64(1)}, {int64(2)}, {int64(3)}}, nil}
+	var ids []string
+	for row, err := range d.TableRows("Patient", "ID") {
+		if err != nil {
+			t.Fatalf("Unexpected error iterating rows: %v", err)
+		}
+		ids = append(ids, row[0])
+		if len(ids) == 2 {
+			break
+		}
+	}
+	if len(ids) != 2 {
+		t.Errorf("Actual number of rows %d is not equal to expected: 2", len(ids))
+	}
+	// The connection is released after breaking out of the loop
+	if _, err := d.ExecuteE("SELECT 1;"); err != nil {
+		t.Errorf("Unexpected error after iteration: %v", err)
+	}
+	for _, err := range d.TableRows("Patients", "*") {
+		if err == nil {
+			t.Error("No error returned for unknown table.")
+		}
+	}
+}
@@ -0,0 +1,213 @@
+// Contains functions for joining tables and discovering foreign keys
+
+package dbIO
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ForeignKey stores a single column of a foreign key constraint. Composite keys are stored as one ForeignKey per column with the same Name.
+type ForeignKey struct {
+	Name      string
+	Table     string
+	Column    string
+	RefTable  string
+	RefColumn string
+}
+
+// GetForeignKeysCtx extracts foreign key constraints from information_schema and stores them in ForeignKeys.
+func (d *DBIO) GetForeignKeysCtx(ctx context.Context) error {
+	cmd := `SELECT CONSTRAINT_NAME,TABLE_NAME,COLUMN_NAME,REFERENCED_TABLE_NAME,REFERENCED_COLUMN_NAME FROM information_schema.KEY_COLUMN_USAGE
+WHERE TABLE_SCHEMA = DATABASE() AND REFERENCED_TABLE_NAME IS NOT NULL ORDER BY TABLE_NAME,CONSTRAINT_NAME,ORDINAL_POSITION;`
+	rows, err := d.ExecuteCtx(ctx, cmd)
+	if err != nil {
+		return fmt.Errorf("extracting foreign keys: %w", err)
+	}
+	d.ForeignKeys = make([]ForeignKey, 0, len(rows))
+	for _, i := range rows {
+		if len(i) == 5 {
+			d.ForeignKeys = append(d.ForeignKeys, ForeignKey{Name: i[0], Table: i[1], Column: i[2], RefTable: i[3], RefColumn: i[4]})
+		}
+	}
+	return nil
+}
+
+// GetForeignKeys extracts foreign key constraints from information_schema and stores them in ForeignKeys.
+func (d *DBIO) GetForeignKeys() error {
+	return d.GetForeignKeysCtx(context.Background())
+}
+
+// Returns the join conditions between table and any of the tables in joined using their foreign keys.
+func (d *DBIO) foreignKeyJoin(ctx context.Context, table string, joined []string) (string, error) {
+	if d.ForeignKeys == nil && d.DB != nil {
+		if err := d.GetForeignKeysCtx(ctx); err != nil {
+			return "", err
+		}
+	}
+	in := make(map[string]bool)
+	for _, i := range joined {
+		in[i] = true
+	}
+	var name string
+	var ret []string
+	for _, i := range d.ForeignKeys {
+		if !(i.Table == table && in[i.RefTable]) && !(i.RefTable == table && in[i.Table]) {
+			continue
+		}
+		key := i.Table + "." + i.Name
+		if len(name) > 0 && name != key {
+			return "", fmt.Errorf("%w: more than one foreign key joins %s to %s; give the join columns explicitly", ErrInvalidQuery, table, strings.Join(joined, ","))
+		}
+		name = key
+		ret = append(ret, fmt.Sprintf("%s = %s", qualify(i.Table, i.Column), qualify(i.RefTable, i.RefColumn)))
+	}
+	if len(ret) == 0 {
+		return "", fmt.Errorf("%w: no foreign key joins %s to %s", ErrInvalidQuery, table, strings.Join(joined, ","))
+	}
+	return strings.Join(ret, " AND "), nil
+}
+
+// Returns quoted, table-qualified column name.
+func qualify(table, column string) string {
+	return quoteIdentifier(table) + "." + quoteIdentifier(column)
+}
+
+// join stores a single JOIN clause.
+type join struct {
+	kind  string
+	table string
+	left  string
+	right string
+}
+
+// Join adds an inner join with table where the left column equals the right column. left is a column of a table which is already
+// in the query (given as table.column, or as a column name if it is unambiguous) and right is a column of table. If left and right
+// are both empty, the join columns are taken from the foreign key between table and the tables already in the query.
+func (s *SelectQuery) Join(table, left, right string) *SelectQuery {
+	s.joins = append(s.joins, join{"JOIN", table, left, right})
+	return s
+}
+
+// LeftJoin adds a left join with table. All rows of the preceding tables are returned, with NULL values for table if there is no
+// matching row. The join columns are given the same as for Join.
+func (s *SelectQuery) LeftJoin(table, left, right string) *SelectQuery {
+	s.joins = append(s.joins, join{"LEFT JOIN", table, left, right})
+	return s
+}
+
+// Returns the first n tables in the query.
+func (s *SelectQuery) tables(n int) []string {
+	ret := []string{s.table}
+	for _, i := range s.joins {
+		if len(ret) >= n {
+			break
+		}
+		ret = append(ret, i.table)
+	}
+	return ret
+}
+
+// Returns the table and column names of name (which may be qualified as table.column) from the given tables.
+func (s *SelectQuery) resolve(ctx context.Context, name string, tables []string) (string, string, error) {
+	name = strings.TrimSpace(name)
+	if t, c, ex := strings.Cut(name, "."); ex {
+		for _, i := range tables {
+			if i == t {
+				c, err := s.d.columnName(ctx, t, c)
+				return t, c, err
+			}
+		}
+		return "", "", fmt.Errorf("%w %q in query", ErrUnknownTable, t)
+	}
+	var table, column string
+	for _, i := range tables {
+		if c, err := s.d.columnName(ctx, i, name); err == nil {
+			if len(table) > 0 {
+				return "", "", fmt.Errorf("%w: column %q is in both %s and %s", ErrInvalidQuery, name, table, i)
+			}
+			table, column = i, c
+		}
+	}
+	if len(table) < 1 {
+		return "", "", fmt.Errorf("%w %q in tables %s", ErrUnknownColumn, name, strings.Join(tables, ","))
+	}
+	return table, column, nil
+}
+
+// Returns quoted column name, qualified with its table name if the query has joins.
+func (s *SelectQuery) column(ctx context.Context, name string) (string, error) {
+	t, c, err := s.resolve(ctx, name, s.tables(len(s.joins)+1))
+	if err != nil {
+		return "", err
+	} else if len(s.joins) == 0 {
+		return quoteIdentifier(c), nil
+	}
+	return qualify(t, c), nil
+}
+
+// Returns select targets. Targets of queries with joins are aliased as table.column.
+func (s *SelectQuery) targets(ctx context.Context) (string, error) {
+	if len(s.joins) == 0 {
+		if len(s.columns) == 0 {
+			return "*", nil
+		}
+		return s.d.checkTargets(ctx, s.table, strings.Join(s.columns, ","))
+	}
+	var ret []string
+	tables := s.tables(len(s.joins) + 1)
+	columns := s.columns
+	if len(columns) == 0 || (len(columns) == 1 && strings.TrimSpace(columns[0]) == "*") {
+		columns = nil
+		for _, t := range tables {
+			c, err := s.d.tableColumns(ctx, t)
+			if err != nil {
+				return "", err
+			}
+			for _, i := range c {
+				columns = append(columns, t+"."+i)
+			}
+		}
+	}
+	for _, i := range columns {
+		for _, j := range strings.Split(i, ",") {
+			t, c, err := s.resolve(ctx, j, tables)
+			if err != nil {
+				return "", err
+			}
+			ret = append(ret, fmt.Sprintf("%s AS %s", qualify(t, c), quoteIdentifier(t+"."+c)))
+		}
+	}
+	return strings.Join(ret, ","), nil
+}
+
+// Returns the JOIN clauses of the query.
+func (s *SelectQuery) joinClauses(ctx context.Context) (string, error) {
+	var b strings.Builder
+	for idx, i := range s.joins {
+		tbl, err := s.d.checkTable(ctx, i.table)
+		if err != nil {
+			return "", err
+		}
+		joined := s.tables(idx + 1)
+		var on string
+		if len(i.left) == 0 && len(i.right) == 0 {
+			if on, err = s.d.foreignKeyJoin(ctx, i.table, joined); err != nil {
+				return "", err
+			}
+		} else {
+			lt, lc, err := s.resolve(ctx, i.left, joined)
+			if err != nil {
+				return "", err
+			}
+			_, rc, err := s.resolve(ctx, i.right, []string{i.table})
+			if err != nil {
+				return "", err
+			}
+			on = fmt.Sprintf("%s = %s", qualify(lt, lc), qualify(i.table, rc))
+		}
+		b.WriteString(fmt.Sprintf(" %s %s ON %s", i.kind, tbl, on))
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,277 @@
+// Defines Paginator struct for paging through tables
+
+package dbIO
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// PageOptions stores options for a Paginator.
+type PageOptions struct {
+	// Target is the comma-seperated list of columns to return. It defaults to all columns.
+	Target string
+	// Keys are the columns used to order the pages. Together they must be unique and not NULL. They default to the table's primary
+	// key. If neither are given, pages are read using OFFSET and ordered by every selected column.
+	Keys []string
+	// Desc returns rows in descending order of Keys.
+	Desc bool
+	// Size is the number of rows in each page. It defaults to 100.
+	Size int
+}
+
+// Page stores a single page of rows.
+type Page struct {
+	Columns []string
+	Rows    [][]string
+	// Next is the token for the following page. It is empty if this is the last page.
+	Next string
+}
+
+// Paginator reads a table one page at a time. Pages after the first are selected with WHERE key > last key (keyset pagination),
+// so each page is read from the index rather than by scanning and discarding the preceding rows.
+type Paginator struct {
+	d      *DBIO
+	table  string
+	target string
+	keys   []string
+	desc   bool
+	size   int
+}
+
+// pageToken stores the position of the next page. Names and Desc record the order of the pages, so tokens from a differently
+// ordered Paginator are rejected.
+type pageToken struct {
+	Table  string   `json:"t"`
+	Names  []string `json:"n,omitempty"`
+	Desc   bool     `json:"d,omitempty"`
+	Keys   []string `json:"k,omitempty"`
+	Offset int      `json:"o,omitempty"`
+}
+
+// Returns the primary key columns of table.
+func (d *DBIO) primaryKey(ctx context.Context, table string) ([]string, error) {
+	cmd := `SELECT COLUMN_NAME FROM information_schema.KEY_COLUMN_USAGE WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?
+AND CONSTRAINT_NAME = 'PRIMARY' ORDER BY ORDINAL_POSITION;`
+	rows, err := d.ExecuteCtx(ctx, cmd, table)
+	if err != nil {
+		return nil, fmt.Errorf("extracting primary key of %s: %w", table, err)
+	}
+	var ret []string
+	for _, i := range rows {
+		ret = append(ret, i[0])
+	}
+	return ret, nil
+}
+
+// NewPaginatorCtx returns a Paginator for table. The primary key is read from information_schema if no keys are given.
+func (d *DBIO) NewPaginatorCtx(ctx context.Context, table string, opts PageOptions) (*Paginator, error) {
+	p := &Paginator{d: d, table: table, target: opts.Target, desc: opts.Desc, size: opts.Size}
+	if _, err := d.checkTable(ctx, table); err != nil {
+		return nil, err
+	}
+	if len(strings.TrimSpace(p.target)) == 0 {
+		p.target = "*"
+	}
+	if _, err := d.checkTargets(ctx, table, p.target); err != nil {
+		return nil, err
+	}
+	if p.size < 1 {
+		p.size = 100
+	}
+	keys := opts.Keys
+	if len(keys) == 0 && d.DB != nil {
+		var err error
+		if keys, err = d.primaryKey(ctx, table); err != nil {
+			return nil, err
+		}
+	}
+	for _, i := range keys {
+		c, err := d.columnName(ctx, table, i)
+		if err != nil {
+			return nil, err
+		}
+		p.keys = append(p.keys, c)
+	}
+	return p, nil
+}
+
+// NewPaginator returns a Paginator for table. The primary key is read from information_schema if no keys are given.
+func (d *DBIO) NewPaginator(table string, opts PageOptions) (*Paginator, error) {
+	return d.NewPaginatorCtx(context.Background(), table, opts)
+}
+
+// Keys returns the key columns, or nil if pages are read using OFFSET.
+func (p *Paginator) Keys() []string {
+	return p.keys
+}
+
+// Returns a token for the first page, which records the order of the pages.
+func (p *Paginator) token() pageToken {
+	return pageToken{Table: p.table, Names: p.keys, Desc: p.desc}
+}
+
+// Returns encoded token.
+func (p *Paginator) encode(t pageToken) string {
+	b, _ := json.Marshal(t)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Returns decoded token. An empty token returns the first page.
+func (p *Paginator) decode(token string) (pageToken, error) {
+	var ret pageToken
+	if len(token) == 0 {
+		return ret, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err == nil {
+		err = json.Unmarshal(b, &ret)
+	}
+	if err != nil || ret.Table != p.table || ret.Desc != p.desc || strings.Join(ret.Names, ",") != strings.Join(p.keys, ",") ||
+		(len(ret.Keys) > 0 && len(ret.Keys) != len(p.keys)) || ret.Offset < 0 {
+		return ret, fmt.Errorf("%w: invalid page token for %s", ErrInvalidQuery, p.table)
+	}
+	return ret, nil
+}
+
+// Returns the WHERE clause selecting rows after the given key values. Composite keys are compared in order, e.g.
+// a > ? OR (a = ? AND b > ?).
+func (p *Paginator) after(last []string) (string, []interface{}) {
+	var terms []string
+	var args []interface{}
+	op := ">"
+	if p.desc {
+		op = "<"
+	}
+	for i := range p.keys {
+		var t []string
+		for j := 0; j < i; j++ {
+			t = append(t, quoteIdentifier(p.keys[j])+" = ?")
+			args = append(args, last[j])
+		}
+		t = append(t, fmt.Sprintf("%s %s ?", quoteIdentifier(p.keys[i]), op))
+		args = append(args, last[i])
+		terms = append(terms, "("+strings.Join(t, " AND ")+")")
+	}
+	return strings.Join(terms, " OR "), args
+}
+
+// Returns the quoted columns which order the pages. Without keys, rows are ordered by every selected column so OFFSET returns
+// them in the same order for each page.
+func (p *Paginator) order(ctx context.Context) ([]string, error) {
+	var ret []string
+	if len(p.keys) > 0 {
+		for _, i := range p.keys {
+			ret = append(ret, quoteIdentifier(i))
+		}
+		return ret, nil
+	}
+	columns := strings.Split(p.target, ",")
+	if strings.TrimSpace(p.target) == "*" {
+		var err error
+		if columns, err = p.d.tableColumns(ctx, p.table); err != nil {
+			return nil, err
+		}
+	}
+	for _, i := range columns {
+		c, err := p.d.checkColumn(ctx, p.table, i)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, c)
+	}
+	return ret, nil
+}
+
+// SQLCtx returns the statement and arguments for the page after token.
+func (p *Paginator) SQLCtx(ctx context.Context, token string) (string, []interface{}, error) {
+	t, err := p.decode(token)
+	if err != nil {
+		return "", nil, err
+	}
+	var args []interface{}
+	tbl, _ := p.d.checkTable(ctx, p.table)
+	target, err := p.d.checkTargets(ctx, p.table, p.target)
+	if err != nil {
+		return "", nil, err
+	}
+	var b strings.Builder
+	b.WriteString("SELECT " + target)
+	for _, i := range p.keys {
+		// Key columns are appended so the next token can be read from the last row
+		b.WriteString("," + quoteIdentifier(i))
+	}
+	b.WriteString(" FROM " + tbl)
+	if len(t.Keys) > 0 {
+		where, a := p.after(t.Keys)
+		b.WriteString(" WHERE " + where)
+		args = append(args, a...)
+	}
+	order, err := p.order(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	for idx, i := range order {
+		if idx == 0 {
+			b.WriteString(" ORDER BY ")
+		} else {
+			b.WriteString(", ")
+		}
+		b.WriteString(i)
+		if p.desc {
+			b.WriteString(" DESC")
+		}
+	}
+	// Request one extra row to determine if there is another page
+	b.WriteString(" LIMIT ?")
+	args = append(args, p.size+1)
+	if len(p.keys) == 0 && t.Offset > 0 {
+		b.WriteString(" OFFSET ?")
+		args = append(args, t.Offset)
+	}
+	return b.String() + ";", args, nil
+}
+
+// PageCtx returns the page following token. An empty token returns the first page.
+func (p *Paginator) PageCtx(ctx context.Context, token string) (*Page, error) {
+	cmd, args, err := p.SQLCtx(ctx, token)
+	if err != nil {
+		return nil, fmt.Errorf("formatting query for %s: %w", p.table, err)
+	}
+	res, err := p.d.queryResult(ctx, cmd, args)
+	if err != nil {
+		return nil, err
+	}
+	n := len(p.keys)
+	names := res.Names()
+	ret := &Page{Columns: names[:len(names)-n]}
+	for idx, i := range res.Rows {
+		if idx == p.size {
+			last := res.Rows[idx-1]
+			next := p.token()
+			if n > 0 {
+				next.Keys = last[len(last)-n:]
+			} else {
+				t, _ := p.decode(token)
+				next.Offset = t.Offset + p.size
+			}
+			ret.Next = p.encode(next)
+			break
+		}
+		ret.Rows = append(ret.Rows, i[:len(i)-n])
+	}
+	return ret, nil
+}
+
+// SQL returns the statement and arguments for the page after token.
+func (p *Paginator) SQL(token string) (string, []interface{}, error) {
+	return p.SQLCtx(context.Background(), token)
+}
+
+// Page returns the page following token. An empty token returns the first page.
+func (p *Paginator) Page(token string) (*Page, error) {
+	return p.PageCtx(context.Background(), token)
+}
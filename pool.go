@@ -0,0 +1,225 @@
+// Contains functions for tuning and monitoring the connection pool
+
+package dbIO
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Applies the pool options in c to db.
+func (c *Config) applyPool(db *sql.DB) {
+	if c.MaxOpenConns != 0 {
+		db.SetMaxOpenConns(c.MaxOpenConns)
+	}
+	if c.MaxIdleConns != 0 {
+		db.SetMaxIdleConns(c.MaxIdleConns)
+	}
+	if c.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(c.ConnMaxLifetime)
+	}
+	if c.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(c.ConnMaxIdleTime)
+	}
+}
+
+// Stats summarizes connection pool usage and the state of the health checker.
+type Stats struct {
+	sql.DBStats
+	// Healthy is false if the last health check failed. It is true if no health checker is running.
+	Healthy bool
+	// LastCheck is the time of the last health check.
+	LastCheck time.Time
+	// LastError is the error returned by the last failed health check.
+	LastError error
+	// Uptime is the time since the connection was established.
+	Uptime time.Duration
+}
+
+// String returns a one line summary of pool usage.
+func (s Stats) String() string {
+	state := "healthy"
+	if !s.Healthy {
+		state = fmt.Sprintf("unhealthy (%v)", s.LastError)
+	}
+	max := "unlimited"
+	if s.MaxOpenConnections > 0 {
+		max = fmt.Sprint(s.MaxOpenConnections)
+	}
+	return fmt.Sprintf("%d/%s open (%d in use, %d idle), %d waits totalling %v, up %v, %s", s.OpenConnections, max, s.InUse, s.Idle,
+		s.WaitCount, s.WaitDuration, s.Uptime.Round(time.Second), state)
+}
+
+// Stats returns a summary of connection pool usage.
+func (d *DBIO) Stats() Stats {
+	ret := Stats{Healthy: true}
+	if d.DB != nil {
+		ret.DBStats = d.DB.Stats()
+	}
+	if !d.Starttime.IsZero() {
+		ret.Uptime = time.Since(d.Starttime)
+	}
+	if h := d.runningCheck(); h != nil {
+		h.Lock()
+		ret.Healthy = h.healthy
+		ret.LastCheck = h.last
+		ret.LastError = h.err
+		h.Unlock()
+	}
+	return ret
+}
+
+// HealthCheck stores options for the background health checker.
+type HealthCheck struct {
+	// Interval is the time between pings. It defaults to 30 seconds.
+	Interval time.Duration
+	// Timeout is the maximum duration of each ping. It defaults to 5 seconds.
+	Timeout time.Duration
+	// OnChange is called with the new state whenever the server becomes reachable or unreachable. err is the ping error
+	// if healthy is false.
+	OnChange func(healthy bool, err error)
+}
+
+// healthChecker stores the state of a running health check.
+type healthChecker struct {
+	sync.Mutex
+	HealthCheck
+	healthy bool
+	last    time.Time
+	err     error
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// healthState stores the running health checker, if any. It is shared with transactions begun from the DBIO.
+type healthState struct {
+	sync.Mutex
+	checker *healthChecker
+}
+
+// Returns the running health checker, or nil if there is none.
+func (d *DBIO) runningCheck() *healthChecker {
+	if d.health == nil {
+		return nil
+	}
+	d.health.Lock()
+	defer d.health.Unlock()
+	return d.health.checker
+}
+
+// Pings the server and records the result. Returns true if the state changed.
+func (h *healthChecker) check(ctx context.Context, db *sql.DB) bool {
+	pctx, cancel := context.WithTimeout(ctx, h.Timeout)
+	err := db.PingContext(pctx)
+	cancel()
+	if ctx.Err() != nil {
+		return false
+	}
+	h.Lock()
+	changed := h.healthy != (err == nil)
+	h.healthy = err == nil
+	h.last = time.Now()
+	h.err = err
+	h.Unlock()
+	return changed
+}
+
+// Closes idle connections which were opened before the server became unreachable.
+func (d *DBIO) flushIdle() {
+	var n int
+	if d.Config != nil {
+		n = d.Config.MaxIdleConns
+	}
+	if n < 0 {
+		return
+	} else if n == 0 {
+		// database/sql default
+		n = 2
+	}
+	d.DB.SetMaxIdleConns(0)
+	d.DB.SetMaxIdleConns(n)
+}
+
+// Pings the server on each interval until ctx is cancelled.
+func (d *DBIO) monitor(ctx context.Context, h *healthChecker) {
+	defer close(h.done)
+	defer func() {
+		// Clear the checker if ctx was cancelled rather than StopHealthCheck being called
+		d.health.Lock()
+		if d.health.checker == h {
+			d.health.checker = nil
+		}
+		d.health.Unlock()
+	}()
+	ticker := time.NewTicker(h.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if h.check(ctx, d.DB) {
+				h.Lock()
+				healthy, err := h.healthy, h.err
+				h.Unlock()
+				if healthy {
+					// The server may have restarted, so replace any stale connections
+					d.flushIdle()
+					d.logger.Println("Connection to database restored.")
+				} else {
+					d.logger.Printf("Lost connection to database: %v\n", err)
+				}
+				if h.OnChange != nil {
+					h.OnChange(healthy, err)
+				}
+			}
+		}
+	}
+}
+
+// StartHealthCheck pings the server in the background on the given interval until ctx is cancelled or StopHealthCheck is called.
+// Idle connections are closed when the server becomes reachable again, so queries are not sent over connections to a server
+// which has restarted. The state of the last check is reported by Stats.
+func (d *DBIO) StartHealthCheck(ctx context.Context, opts HealthCheck) error {
+	if d.DB == nil {
+		return fmt.Errorf("%w: starting health check before connecting", ErrConnection)
+	}
+	if d.health == nil {
+		// DBIO was not initialized by NewDBIO
+		d.health = new(healthState)
+	}
+	d.health.Lock()
+	defer d.health.Unlock()
+	if d.health.checker != nil {
+		return errors.New("health check is already running")
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = 30 * time.Second
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+	h := &healthChecker{HealthCheck: opts, healthy: true, done: make(chan struct{})}
+	ctx, h.cancel = context.WithCancel(ctx)
+	d.health.checker = h
+	go d.monitor(ctx, h)
+	return nil
+}
+
+// StopHealthCheck stops the background health checker and waits for it to exit.
+func (d *DBIO) StopHealthCheck() {
+	// The lock is not held while waiting, since OnChange may call Stats
+	if h := d.runningCheck(); h != nil {
+		h.cancel()
+		<-h.done
+		d.health.Lock()
+		if d.health.checker == h {
+			d.health.checker = nil
+		}
+		d.health.Unlock()
+	}
+}
@@ -0,0 +1,226 @@
+// Contains functions for validating identifiers and building parameterized queries
+
+package dbIO
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Comparison operators which may be used in WHERE clauses.
+var operators = map[string]string{
+	"=":        "=",
+	"!=":       "!=",
+	"<>":       "<>",
+	"<":        "<",
+	"<=":       "<=",
+	">":        ">",
+	">=":       ">=",
+	"<=>":      "<=>",
+	"LIKE":     "LIKE",
+	"NOT LIKE": "NOT LIKE",
+}
+
+// Wraps identifier in backticks and escapes any backticks within it
+func quoteIdentifier(name string) string {
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
+
+// Returns string of n comma-seperated placeholders
+func placeholders(n int) string {
+	if n < 1 {
+		return ""
+	}
+	return strings.Repeat("?,", n-1) + "?"
+}
+
+// Returns op in upper case if it is a supported comparison operator
+func checkOperator(op string) (string, error) {
+	o := strings.ToUpper(strings.Join(strings.Fields(op), " "))
+	if ret, ex := operators[o]; ex {
+		return ret, nil
+	}
+	return "", fmt.Errorf("%w: unsupported operator %q", ErrInvalidQuery, op)
+}
+
+// Returns column names for table, reading them from the database if Columns has not been populated.
+func (d *DBIO) tableColumns(ctx context.Context, table string) ([]string, error) {
+	if d.Columns == nil && d.DB != nil {
+		if err := d.GetTableColumnsCtx(ctx); err != nil {
+			return nil, err
+		}
+	}
+	columns, ex := d.Columns[table]
+	if !ex {
+		return nil, fmt.Errorf("%w %q", ErrUnknownTable, table)
+	}
+	return strings.Split(columns, ","), nil
+}
+
+// Returns quoted table name if table is in Columns.
+func (d *DBIO) checkTable(ctx context.Context, table string) (string, error) {
+	if _, err := d.tableColumns(ctx, table); err != nil {
+		return "", err
+	}
+	return quoteIdentifier(table), nil
+}
+
+// Returns the column name as it is stored in Columns if column is in table.
+func (d *DBIO) columnName(ctx context.Context, table, column string) (string, error) {
+	columns, err := d.tableColumns(ctx, table)
+	if err != nil {
+		return "", err
+	}
+	column = strings.TrimSpace(column)
+	for _, i := range columns {
+		// MySQL column names are not case sensitive
+		if strings.EqualFold(i, column) {
+			return i, nil
+		}
+	}
+	return "", fmt.Errorf("%w %q in table %q", ErrUnknownColumn, column, table)
+}
+
+// Returns quoted column name if column is in table.
+func (d *DBIO) checkColumn(ctx context.Context, table, column string) (string, error) {
+	ret, err := d.columnName(ctx, table, column)
+	if err != nil {
+		return "", err
+	}
+	return quoteIdentifier(ret), nil
+}
+
+// Returns quoted, comma-seperated string of target columns. An asterisk is returned unchanged.
+func (d *DBIO) checkTargets(ctx context.Context, table, target string) (string, error) {
+	if strings.TrimSpace(target) == "*" {
+		if _, err := d.tableColumns(ctx, table); err != nil {
+			return "", err
+		}
+		return "*", nil
+	}
+	var ret []string
+	for _, i := range strings.Split(target, ",") {
+		c, err := d.checkColumn(ctx, table, i)
+		if err != nil {
+			return "", err
+		}
+		ret = append(ret, c)
+	}
+	return strings.Join(ret, ","), nil
+}
+
+// Splits comma-seperated keys and removes any apostrophes wrapping individual terms.
+func splitKeys(key string) []interface{} {
+	var ret []interface{}
+	for _, i := range strings.Split(key, ",") {
+		i = strings.TrimSpace(i)
+		if len(i) >= 2 && strings.HasPrefix(i, "'") && strings.HasSuffix(i, "'") {
+			i = i[1 : len(i)-1]
+		}
+		ret = append(ret, i)
+	}
+	return ret
+}
+
+// Returns SELECT statement for target columns from table with the given WHERE clause.
+func (d *DBIO) selectQuery(ctx context.Context, table, target, where string) (string, error) {
+	tbl, err := d.checkTable(ctx, table)
+	if err != nil {
+		return "", err
+	}
+	t, err := d.checkTargets(ctx, table, target)
+	if err != nil {
+		return "", err
+	}
+	cmd := fmt.Sprintf("SELECT %s FROM %s", t, tbl)
+	if len(where) > 0 {
+		cmd += " WHERE " + where
+	}
+	return cmd + ";", nil
+}
+
+// Returns query and arguments for GetRows.
+func (d *DBIO) getRowsQuery(ctx context.Context, table, column, key, target string) (string, []interface{}, error) {
+	col, err := d.checkColumn(ctx, table, column)
+	if err != nil {
+		return "", nil, err
+	}
+	var where string
+	var args []interface{}
+	if strings.Contains(key, ",") {
+		// Format for list
+		args = splitKeys(key)
+		where = fmt.Sprintf("%s IN (%s)", col, placeholders(len(args)))
+	} else {
+		args = []interface{}{key}
+		where = col + " = ?"
+	}
+	cmd, err := d.selectQuery(ctx, table, target, where)
+	return cmd, args, err
+}
+
+// Returns query and arguments for EvaluateRows and GetRowsMin.
+func (d *DBIO) evaluateQuery(ctx context.Context, table, column, op string, key interface{}, target string) (string, []interface{}, error) {
+	col, err := d.checkColumn(ctx, table, column)
+	if err != nil {
+		return "", nil, err
+	}
+	o, err := checkOperator(op)
+	if err != nil {
+		return "", nil, err
+	}
+	cmd, err := d.selectQuery(ctx, table, target, fmt.Sprintf("%s %s ?", col, o))
+	return cmd, []interface{}{key}, err
+}
+
+// Returns query and arguments for ColumnContains.
+func (d *DBIO) containsQuery(ctx context.Context, table, column, value, target string) (string, []interface{}, error) {
+	col, err := d.checkColumn(ctx, table, column)
+	if err != nil {
+		return "", nil, err
+	}
+	cmd, err := d.selectQuery(ctx, table, target, fmt.Sprintf("INSTR(%s, ?) > 0", col))
+	return cmd, []interface{}{value}, err
+}
+
+// Returns query and arguments for Count.
+func (d *DBIO) countQuery(ctx context.Context, table, column, target, op, key string, distinct bool) (string, []interface{}, error) {
+	var args []interface{}
+	tbl, err := d.checkTable(ctx, table)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(strings.TrimSpace(target)) == 0 {
+		target = "*"
+	}
+	t, err := d.checkTargets(ctx, table, target)
+	if err != nil {
+		return "", nil, err
+	}
+	if distinct {
+		if t == "*" {
+			// COUNT(DISTINCT *) is not valid SQL
+			return "", nil, fmt.Errorf("%w: please specify target columns to count distinct values", ErrInvalidQuery)
+		}
+		t = "DISTINCT " + t
+	}
+	cmd := fmt.Sprintf("SELECT COUNT(%s) FROM %s", t, tbl)
+	if len(op) >= 1 || len(key) >= 1 || len(column) >= 1 {
+		if len(op) < 1 || len(key) < 1 || len(column) < 1 {
+			return "", nil, fmt.Errorf("%w: please specify target column, operator, and target value", ErrInvalidQuery)
+		}
+		// Add evaluation statement
+		col, err := d.checkColumn(ctx, table, column)
+		if err != nil {
+			return "", nil, err
+		}
+		o, err := checkOperator(op)
+		if err != nil {
+			return "", nil, err
+		}
+		cmd += fmt.Sprintf(" WHERE %s %s ?", col, o)
+		args = append(args, key)
+	}
+	return cmd + ";", args, nil
+}
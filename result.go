@@ -0,0 +1,271 @@
+// Defines ResultSet struct for query results with column metadata
+
+package dbIO
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Column describes a column of a ResultSet.
+type Column struct {
+	// Name is the column name (or alias) returned by the server.
+	Name string
+	// Type is the database type name (e.g. VARCHAR, INT, DECIMAL). It is empty if the driver does not report it.
+	Type string
+	// Nullable is false if the column is declared NOT NULL. It is true if the driver does not report nullability.
+	Nullable bool
+}
+
+// ResultSet stores the rows of a query result along with the name and type of each column. Values are converted to strings the
+// same as for Execute, with NULL values stored as DBIO.NullValue.
+type ResultSet struct {
+	Columns []Column
+	Rows    [][]string
+	nulls   [][]bool
+}
+
+// Reads all rows into a ResultSet.
+func (d *DBIO) readResult(rows *sql.Rows) (*ResultSet, error) {
+	sc, err := d.newRowScanner(rows)
+	if err != nil {
+		return nil, err
+	}
+	ret := &ResultSet{Columns: sc.columns}
+	for rows.Next() {
+		r, n, err := sc.scan(rows)
+		if err != nil {
+			return ret, err
+		}
+		ret.Rows = append(ret.Rows, r)
+		ret.nulls = append(ret.nulls, n)
+	}
+	return ret, rows.Err()
+}
+
+// Len returns the number of rows.
+func (r *ResultSet) Len() int {
+	return len(r.Rows)
+}
+
+// Names returns the column names.
+func (r *ResultSet) Names() []string {
+	ret := make([]string, len(r.Columns))
+	for i, c := range r.Columns {
+		ret[i] = c.Name
+	}
+	return ret
+}
+
+// Index returns the index of the named column (ignoring case), or -1 if it is not present.
+func (r *ResultSet) Index(column string) int {
+	for i, c := range r.Columns {
+		if strings.EqualFold(c.Name, column) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Value returns the value of the named column in the given row. Returns false if the row or column is not present.
+func (r *ResultSet) Value(row int, column string) (string, bool) {
+	idx := r.Index(column)
+	if idx < 0 || row < 0 || row >= len(r.Rows) {
+		return "", false
+	}
+	return r.Rows[row][idx], true
+}
+
+// IsNull returns true if the value of the named column in the given row is NULL.
+func (r *ResultSet) IsNull(row int, column string) bool {
+	idx := r.Index(column)
+	if idx < 0 || row < 0 || row >= len(r.nulls) {
+		return false
+	}
+	return r.nulls[row][idx]
+}
+
+// Column returns all values of the named column. Returns nil if it is not present.
+func (r *ResultSet) Column(column string) []string {
+	idx := r.Index(column)
+	if idx < 0 {
+		return nil
+	}
+	ret := make([]string, len(r.Rows))
+	for i, row := range r.Rows {
+		ret[i] = row[idx]
+	}
+	return ret
+}
+
+// Maps returns a map of column name to value for each row.
+func (r *ResultSet) Maps() []map[string]string {
+	ret := make([]map[string]string, len(r.Rows))
+	for i, row := range r.Rows {
+		ret[i] = make(map[string]string, len(r.Columns))
+		for j, c := range r.Columns {
+			ret[i][c.Name] = row[j]
+		}
+	}
+	return ret
+}
+
+// Project returns a new ResultSet containing only the named columns, in the given order.
+func (r *ResultSet) Project(columns ...string) (*ResultSet, error) {
+	idx := make([]int, len(columns))
+	ret := &ResultSet{Columns: make([]Column, len(columns))}
+	for i, c := range columns {
+		if idx[i] = r.Index(c); idx[i] < 0 {
+			return nil, fmt.Errorf("%w %q in result set", ErrUnknownColumn, c)
+		}
+		ret.Columns[i] = r.Columns[idx[i]]
+	}
+	for i, row := range r.Rows {
+		p := make([]string, len(idx))
+		n := make([]bool, len(idx))
+		for j, k := range idx {
+			p[j] = row[k]
+			if i < len(r.nulls) {
+				n[j] = r.nulls[i][k]
+			}
+		}
+		ret.Rows = append(ret.Rows, p)
+		ret.nulls = append(ret.nulls, n)
+	}
+	return ret, nil
+}
+
+// Rename changes column names using the given map of old name to new name.
+func (r *ResultSet) Rename(names map[string]string) error {
+	for k, v := range names {
+		idx := r.Index(k)
+		if idx < 0 {
+			return fmt.Errorf("%w %q in result set", ErrUnknownColumn, k)
+		}
+		r.Columns[idx].Name = v
+	}
+	return nil
+}
+
+// Writes a row of the text table padded to the given widths.
+func writeTableRow(b *strings.Builder, row []string, widths []int) {
+	b.WriteByte('|')
+	for i, v := range row {
+		b.WriteString(" " + v + strings.Repeat(" ", widths[i]-utf8.RuneCountInString(v)) + " |")
+	}
+	b.WriteByte('\n')
+}
+
+// WriteTable writes the result set to w as a text table in the same format as the mysql client.
+func (r *ResultSet) WriteTable(w io.Writer) error {
+	var b strings.Builder
+	names := r.Names()
+	widths := make([]int, len(names))
+	for i, n := range names {
+		widths[i] = utf8.RuneCountInString(n)
+	}
+	for _, row := range r.Rows {
+		for i, v := range row {
+			if l := utf8.RuneCountInString(v); l > widths[i] {
+				widths[i] = l
+			}
+		}
+	}
+	sep := "+"
+	for _, i := range widths {
+		sep += strings.Repeat("-", i+2) + "+"
+	}
+	sep += "\n"
+	b.WriteString(sep)
+	writeTableRow(&b, names, widths)
+	b.WriteString(sep)
+	for _, row := range r.Rows {
+		writeTableRow(&b, row, widths)
+	}
+	if len(r.Rows) > 0 {
+		b.WriteString(sep)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// String returns the result set as a text table.
+func (r *ResultSet) String() string {
+	var b strings.Builder
+	r.WriteTable(&b)
+	return b.String()
+}
+
+// Submits the given query and returns the result.
+func (d *DBIO) queryResult(ctx context.Context, cmd string, args []interface{}) (*ResultSet, error) {
+	var ret *ResultSet
+	err := d.retry(ctx, func() error {
+		rows, err := d.conn().QueryContext(ctx, cmd, args...)
+		if err != nil {
+			return fmt.Errorf("executing '%s': %w", cmd, err)
+		}
+		defer rows.Close()
+		if ret, err = d.readResult(rows); err != nil {
+			return fmt.Errorf("reading results of '%s': %w", cmd, err)
+		}
+		return nil
+	})
+	return ret, err
+}
+
+// ExecuteResultCtx submits the given command as a MySQL query and returns the result with column metadata. Any arguments will be
+// bound to ? placeholders in cmd.
+func (d *DBIO) ExecuteResultCtx(ctx context.Context, cmd string, args ...interface{}) (*ResultSet, error) {
+	return d.queryResult(ctx, cmd, args)
+}
+
+// ExecuteResult submits the given command as a MySQL query and returns the result with column metadata.
+func (d *DBIO) ExecuteResult(cmd string, args ...interface{}) (*ResultSet, error) {
+	return d.ExecuteResultCtx(context.Background(), cmd, args...)
+}
+
+// GetRowsResultCtx returns rows of target columns with key in column, with column metadata. Key may be a comma-seperated list of values.
+func (d *DBIO) GetRowsResultCtx(ctx context.Context, table, column, key, target string) (*ResultSet, error) {
+	cmd, args, err := d.getRowsQuery(ctx, table, column, key, target)
+	if err != nil {
+		return nil, fmt.Errorf("formatting query for %s: %w", table, err)
+	}
+	return d.queryResult(ctx, cmd, args)
+}
+
+// GetRowsResult returns rows of target columns with key in column, with column metadata.
+func (d *DBIO) GetRowsResult(table, column, key, target string) (*ResultSet, error) {
+	return d.GetRowsResultCtx(context.Background(), table, column, key, target)
+}
+
+// GetTableResultCtx returns all contents of the given table with column metadata.
+func (d *DBIO) GetTableResultCtx(ctx context.Context, table string) (*ResultSet, error) {
+	cmd, err := d.selectQuery(ctx, table, "*", "")
+	if err != nil {
+		return nil, fmt.Errorf("formatting query for %s: %w", table, err)
+	}
+	return d.queryResult(ctx, cmd, nil)
+}
+
+// GetTableResult returns all contents of the given table with column metadata.
+func (d *DBIO) GetTableResult(table string) (*ResultSet, error) {
+	return d.GetTableResultCtx(context.Background(), table)
+}
+
+// ResultCtx submits the statement and returns the result with column metadata.
+func (s *SelectQuery) ResultCtx(ctx context.Context) (*ResultSet, error) {
+	cmd, args, err := s.SQLCtx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("formatting query for %s: %w", s.table, err)
+	}
+	return s.d.queryResult(ctx, cmd, args)
+}
+
+// Result submits the statement and returns the result with column metadata.
+func (s *SelectQuery) Result() (*ResultSet, error) {
+	return s.ResultCtx(context.Background())
+}
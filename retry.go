@@ -0,0 +1,82 @@
+// Contains functions for retrying statements which fail with transient errors
+
+package dbIO
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy determines how statements which fail with transient errors (e.g. deadlocks, lock wait timeouts, and lost
+// connections) are retried. Statements within a transaction are never retried, since the server rolls back the whole
+// transaction after a deadlock.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times a statement is submitted, including the first attempt.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry. The delay doubles after each attempt. It defaults to 100 milliseconds.
+	BaseDelay time.Duration
+	// MaxDelay is the maximum delay between attempts. It defaults to 5 seconds.
+	MaxDelay time.Duration
+	// Retryable returns true if a statement which failed with err should be retried. It defaults to IsRetryable. Note that a
+	// statement may have been applied by the server if the connection was lost after it was sent, so a non-idempotent statement
+	// may be repeated when lost connections are retried.
+	Retryable func(err error) bool
+}
+
+// DefaultRetryPolicy returns a policy which makes up to 5 attempts with delays between 100 milliseconds and 5 seconds.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{MaxAttempts: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: 5 * time.Second}
+}
+
+// Returns the delay before the given retry (starting from 1). Half of the delay is randomized so concurrent clients do not retry in step.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	base, max := p.BaseDelay, p.MaxDelay
+	if base <= 0 {
+		base = 100 * time.Millisecond
+	}
+	if max <= 0 {
+		max = 5 * time.Second
+	}
+	delay := base
+	for i := 1; i < attempt && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// Returns true if err should be retried.
+func (p *RetryPolicy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return IsRetryable(err)
+}
+
+// Calls fn until it succeeds or returns an error which should not be retried, the retry policy's attempts are exhausted,
+// or ctx is cancelled.
+func (d *DBIO) retry(ctx context.Context, fn func() error) error {
+	p := d.Retry
+	if p == nil || d.tx != nil {
+		return fn()
+	}
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil || attempt >= p.MaxAttempts || !p.retryable(err) {
+			return err
+		}
+		delay := p.backoff(attempt)
+		d.logger.Printf("Retrying in %v (attempt %d of %d): %v\n", delay.Round(time.Millisecond), attempt+1, p.MaxAttempts, err)
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Contains generic functions for scanning query results into structs
+
+package dbIO
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// ErrUnmappedColumn is returned when a result column has no matching struct field.
+var ErrUnmappedColumn = errors.New("dbIO: unmapped column")
+
+// structInfo stores the index of the field for each column name of a struct type.
+type structInfo struct {
+	fields map[string][]int
+}
+
+// Caches structInfo for each type.
+var structCache sync.Map
+
+// Adds the fields of t to info. Fields of embedded structs are added as if they were fields of t.
+func (info *structInfo) addFields(t reflect.Type, index []int) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("db")
+		if tag == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+		idx := append(append([]int(nil), index...), i)
+		if f.Anonymous && len(tag) == 0 && f.Type.Kind() == reflect.Struct {
+			info.addFields(f.Type, idx)
+			continue
+		} else if !f.IsExported() {
+			continue
+		}
+		name := tag
+		if len(name) == 0 {
+			name = f.Name
+		}
+		name = strings.ToLower(name)
+		if _, ex := info.fields[name]; !ex || len(idx) <= len(info.fields[name]) {
+			// Fields of the outer struct take precedence over embedded fields
+			info.fields[name] = idx
+		}
+	}
+}
+
+// Returns the cached structInfo for t.
+func getStructInfo(t reflect.Type) (*structInfo, error) {
+	if v, ex := structCache.Load(t); ex {
+		return v.(*structInfo), nil
+	} else if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: cannot scan rows into %s; it is not a struct", ErrInvalidQuery, t)
+	}
+	info := &structInfo{fields: make(map[string][]int)}
+	info.addFields(t, nil)
+	v, _ := structCache.LoadOrStore(t, info)
+	return v.(*structInfo), nil
+}
+
+// Returns the field index for each column. Returns an error listing any columns without a field.
+func (info *structInfo) indices(t reflect.Type, columns []string) ([][]int, error) {
+	var missing []string
+	ret := make([][]int, len(columns))
+	for i, c := range columns {
+		idx, ex := info.fields[strings.ToLower(c)]
+		if !ex {
+			missing = append(missing, c)
+		}
+		ret[i] = idx
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: %s has no field for %s", ErrUnmappedColumn, t, strings.Join(missing, ", "))
+	}
+	return ret, nil
+}
+
+// QueryCtx submits query and scans each row of the result into a T, which must be a struct. Columns are matched to the field with
+// the same `db:"name"` tag, or to the untagged field with the same name (ignoring case). Fields tagged `db:"-"` are skipped. NULL
+// values may be scanned into pointer or sql.Null* fields, and time.Time fields are parsed even if Config.ParseTime is false (see
+// GetColumnCtx). Returns an error wrapping ErrUnmappedColumn if any column has no field.
+func QueryCtx[T any](ctx context.Context, d *DBIO, query string, args ...interface{}) ([]T, error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	info, err := getStructInfo(t)
+	if err != nil {
+		return nil, err
+	}
+	var ret []T
+	err = d.retry(ctx, func() error {
+		ret = nil
+		rows, err := d.conn().QueryContext(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("executing '%s': %w", query, err)
+		}
+		defer rows.Close()
+		columns, err := rows.Columns()
+		if err != nil {
+			return fmt.Errorf("reading columns of '%s': %w", query, err)
+		}
+		idx, err := info.indices(t, columns)
+		if err != nil {
+			return err
+		}
+		dest := make([]interface{}, len(idx))
+		for rows.Next() {
+			var v T
+			rv := reflect.ValueOf(&v).Elem()
+			for i, j := range idx {
+				dest[i] = rv.FieldByIndex(j).Addr().Interface()
+			}
+			if err = d.scanValues(rows, dest...); err != nil {
+				return fmt.Errorf("reading results of '%s': %w", query, err)
+			}
+			ret = append(ret, v)
+		}
+		return rows.Err()
+	})
+	return ret, err
+}
+
+// Query submits query and scans each row of the result into a T. See QueryCtx for how columns are mapped to fields.
+func Query[T any](d *DBIO, query string, args ...interface{}) ([]T, error) {
+	return QueryCtx[T](context.Background(), d, query, args...)
+}
+
+// GetTableAsCtx returns the contents of table as a slice of T. See QueryCtx for how columns are mapped to fields.
+func GetTableAsCtx[T any](ctx context.Context, d *DBIO, table string) ([]T, error) {
+	cmd, err := d.selectQuery(ctx, table, "*", "")
+	if err != nil {
+		return nil, fmt.Errorf("formatting query for %s: %w", table, err)
+	}
+	return QueryCtx[T](ctx, d, cmd)
+}
+
+// GetTableAs returns the contents of table as a slice of T. See QueryCtx for how columns are mapped to fields.
+func GetTableAs[T any](d *DBIO, table string) ([]T, error) {
+	return GetTableAsCtx[T](context.Background(), d, table)
+}
+
+// SelectAsCtx submits the SELECT statement and scans each row into a T. See QueryCtx for how columns are mapped to fields.
+func SelectAsCtx[T any](ctx context.Context, s *SelectQuery) ([]T, error) {
+	cmd, args, err := s.SQLCtx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("formatting query for %s: %w", s.table, err)
+	}
+	return QueryCtx[T](ctx, s.d, cmd, args...)
+}
+
+// SelectAs submits the SELECT statement and scans each row into a T. See QueryCtx for how columns are mapped to fields.
+func SelectAs[T any](s *SelectQuery) ([]T, error) {
+	return SelectAsCtx[T](context.Background(), s)
+}
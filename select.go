@@ -0,0 +1,273 @@
+// Defines a query builder for SELECT statements
+
+package dbIO
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// condition stores a single predicate in a WHERE clause.
+type condition struct {
+	conj   string
+	column string
+	op     string
+	value  interface{}
+}
+
+// ordering stores a single term in an ORDER BY clause.
+type ordering struct {
+	column string
+	desc   bool
+}
+
+// SelectQuery builds a parameterized SELECT statement. Create one with DBIO.Select. Identifiers are validated against DBIO.Columns
+// when the statement is built, so errors are returned by SQL, Rows, or Maps rather than by the builder methods. Columns may be
+// qualified with their table name (e.g. Patient.ID), which is required if a column name is in more than one joined table. Columns
+// in the results of queries with joins are named table.column.
+type SelectQuery struct {
+	d        *DBIO
+	columns  []string
+	table    string
+	distinct bool
+	joins    []join
+	where    []condition
+	order    []ordering
+	limit    int
+	offset   int
+}
+
+// Select starts a SELECT statement for the given columns. All columns are selected if none are given (or if the only column is *).
+func (d *DBIO) Select(columns ...string) *SelectQuery {
+	return &SelectQuery{d: d, columns: columns, limit: -1}
+}
+
+// From sets the table to select from.
+func (s *SelectQuery) From(table string) *SelectQuery {
+	s.table = table
+	return s
+}
+
+// Distinct removes duplicate rows from the result.
+func (s *SelectQuery) Distinct() *SelectQuery {
+	s.distinct = true
+	return s
+}
+
+// Where adds a condition comparing column to value. Any operator accepted by EvaluateRows may be used, as well as IN and NOT IN
+// (value must be a slice), and IS NULL and IS NOT NULL (value is ignored). Where is equivalent to And.
+func (s *SelectQuery) Where(column, op string, value interface{}) *SelectQuery {
+	return s.And(column, op, value)
+}
+
+// And adds a condition which must be true in addition to the previous conditions.
+func (s *SelectQuery) And(column, op string, value interface{}) *SelectQuery {
+	s.where = append(s.where, condition{"AND", column, op, value})
+	return s
+}
+
+// Or adds a condition which may be true instead of the previous conditions. Conditions follow SQL precedence, so
+// Where(a).And(b).Or(c) is evaluated as (a AND b) OR c.
+func (s *SelectQuery) Or(column, op string, value interface{}) *SelectQuery {
+	s.where = append(s.where, condition{"OR", column, op, value})
+	return s
+}
+
+// OrderBy sorts the results by the given columns in ascending order.
+func (s *SelectQuery) OrderBy(columns ...string) *SelectQuery {
+	for _, i := range columns {
+		s.order = append(s.order, ordering{column: i})
+	}
+	return s
+}
+
+// OrderByDesc sorts the results by the given columns in descending order.
+func (s *SelectQuery) OrderByDesc(columns ...string) *SelectQuery {
+	for _, i := range columns {
+		s.order = append(s.order, ordering{column: i, desc: true})
+	}
+	return s
+}
+
+// Limit sets the maximum number of rows to return.
+func (s *SelectQuery) Limit(n int) *SelectQuery {
+	s.limit = n
+	return s
+}
+
+// Offset skips the first n rows of the result.
+func (s *SelectQuery) Offset(n int) *SelectQuery {
+	s.offset = n
+	return s
+}
+
+// Returns value as a slice of arguments for an IN clause.
+func inValues(value interface{}) ([]interface{}, error) {
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("%w: IN requires a slice of values, not %T", ErrInvalidQuery, value)
+	} else if v.Len() == 0 {
+		return nil, fmt.Errorf("%w: IN requires at least one value", ErrInvalidQuery)
+	}
+	ret := make([]interface{}, v.Len())
+	for i := range ret {
+		ret[i] = v.Index(i).Interface()
+	}
+	return ret, nil
+}
+
+// Returns the predicate and arguments for c.
+func (s *SelectQuery) predicate(ctx context.Context, c condition) (string, []interface{}, error) {
+	col, err := s.column(ctx, c.column)
+	if err != nil {
+		return "", nil, err
+	}
+	switch op := strings.ToUpper(strings.Join(strings.Fields(c.op), " ")); op {
+	case "IS NULL", "IS NOT NULL":
+		return fmt.Sprintf("%s %s", col, op), nil, nil
+	case "IN", "NOT IN":
+		args, err := inValues(c.value)
+		if err != nil {
+			return "", nil, err
+		}
+		return fmt.Sprintf("%s %s (%s)", col, op, placeholders(len(args))), args, nil
+	}
+	o, err := checkOperator(c.op)
+	if err != nil {
+		return "", nil, err
+	}
+	return fmt.Sprintf("%s %s ?", col, o), []interface{}{c.value}, nil
+}
+
+// Returns the WHERE clause (with a leading space) and its arguments. Returns an empty string if there are no conditions.
+func (s *SelectQuery) whereClause(ctx context.Context) (string, []interface{}, error) {
+	var b strings.Builder
+	var args []interface{}
+	for idx, i := range s.where {
+		p, a, err := s.predicate(ctx, i)
+		if err != nil {
+			return "", nil, err
+		}
+		if idx == 0 {
+			b.WriteString(" WHERE ")
+		} else {
+			b.WriteString(fmt.Sprintf(" %s ", i.conj))
+		}
+		b.WriteString(p)
+		args = append(args, a...)
+	}
+	return b.String(), args, nil
+}
+
+// SQLCtx returns the statement and its arguments.
+func (s *SelectQuery) SQLCtx(ctx context.Context) (string, []interface{}, error) {
+	var b strings.Builder
+	if len(s.table) < 1 {
+		return "", nil, fmt.Errorf("%w: no table given to select from", ErrInvalidQuery)
+	}
+	tbl, err := s.d.checkTable(ctx, s.table)
+	if err != nil {
+		return "", nil, err
+	}
+	target, err := s.targets(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	joins, err := s.joinClauses(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	b.WriteString("SELECT ")
+	if s.distinct {
+		b.WriteString("DISTINCT ")
+	}
+	b.WriteString(fmt.Sprintf("%s FROM %s%s", target, tbl, joins))
+	where, args, err := s.whereClause(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	b.WriteString(where)
+	for idx, i := range s.order {
+		col, err := s.column(ctx, i.column)
+		if err != nil {
+			return "", nil, err
+		}
+		if idx == 0 {
+			b.WriteString(" ORDER BY ")
+		} else {
+			b.WriteString(", ")
+		}
+		b.WriteString(col)
+		if i.desc {
+			b.WriteString(" DESC")
+		}
+	}
+	if s.limit >= 0 {
+		b.WriteString(" LIMIT ?")
+		args = append(args, s.limit)
+	} else if s.offset > 0 {
+		// MySQL requires a limit with an offset
+		b.WriteString(" LIMIT 18446744073709551615")
+	}
+	if s.offset > 0 {
+		b.WriteString(" OFFSET ?")
+		args = append(args, s.offset)
+	}
+	b.WriteByte(';')
+	return b.String(), args, nil
+}
+
+// SQL returns the statement and its arguments.
+func (s *SelectQuery) SQL() (string, []interface{}, error) {
+	return s.SQLCtx(context.Background())
+}
+
+// Builds and submits the statement.
+func (s *SelectQuery) run(ctx context.Context) ([]string, [][]string, error) {
+	cmd, args, err := s.SQLCtx(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("formatting query for %s: %w", s.table, err)
+	}
+	ret, err := s.d.queryResult(ctx, cmd, args)
+	if ret == nil {
+		return nil, nil, err
+	}
+	return ret.Names(), ret.Rows, err
+}
+
+// RowsCtx submits the statement and returns the results in the same form as Execute.
+func (s *SelectQuery) RowsCtx(ctx context.Context) ([][]string, error) {
+	_, ret, err := s.run(ctx)
+	return ret, err
+}
+
+// Rows submits the statement and returns the results in the same form as Execute.
+func (s *SelectQuery) Rows() ([][]string, error) {
+	return s.RowsCtx(context.Background())
+}
+
+// RowsWithHeaderCtx submits the statement and returns the column names and rows of the result.
+func (s *SelectQuery) RowsWithHeaderCtx(ctx context.Context) ([]string, [][]string, error) {
+	return s.run(ctx)
+}
+
+// RowsWithHeader submits the statement and returns the column names and rows of the result.
+func (s *SelectQuery) RowsWithHeader() ([]string, [][]string, error) {
+	return s.RowsWithHeaderCtx(context.Background())
+}
+
+// MapsCtx submits the statement and returns a map of column name to value for each row.
+func (s *SelectQuery) MapsCtx(ctx context.Context) ([]map[string]string, error) {
+	ret, err := s.ResultCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ret.Maps(), nil
+}
+
+// Maps submits the statement and returns a map of column name to value for each row.
+func (s *SelectQuery) Maps() ([]map[string]string, error) {
+	return s.MapsCtx(context.Background())
+}
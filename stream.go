@@ -0,0 +1,163 @@
+// Contains functions for streaming query results one row at a time
+
+package dbIO
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrStop may be returned by the function given to Each or EachRow to stop iterating without returning an error.
+var ErrStop = errors.New("dbIO: stop iteration")
+
+// RowIterator reads the rows of a query result one at a time, so only the current row is held in memory. It must be closed
+// after use (Each closes it automatically).
+type RowIterator struct {
+	// Columns describes the columns of the result.
+	Columns []Column
+	rows    *sql.Rows
+	scanner *rowScanner
+	row     []string
+	nulls   []bool
+	err     error
+}
+
+// StreamCtx submits the given query and returns an iterator over its rows. Any arguments will be bound to ? placeholders in cmd.
+// Submitting the query is retried according to DBIO.Retry, but errors while reading rows are not.
+func (d *DBIO) StreamCtx(ctx context.Context, cmd string, args ...interface{}) (*RowIterator, error) {
+	var rows *sql.Rows
+	err := d.retry(ctx, func() error {
+		var err error
+		rows, err = d.conn().QueryContext(ctx, cmd, args...)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("executing '%s': %w", cmd, err)
+	}
+	sc, err := d.newRowScanner(rows)
+	if err != nil {
+		rows.Close()
+		return nil, fmt.Errorf("reading columns of '%s': %w", cmd, err)
+	}
+	return &RowIterator{Columns: sc.columns, rows: rows, scanner: sc}, nil
+}
+
+// Stream submits the given query and returns an iterator over its rows.
+func (d *DBIO) Stream(cmd string, args ...interface{}) (*RowIterator, error) {
+	return d.StreamCtx(context.Background(), cmd, args...)
+}
+
+// StreamTableCtx returns an iterator over the target columns of table. Use "*" to select entire rows.
+func (d *DBIO) StreamTableCtx(ctx context.Context, table, target string) (*RowIterator, error) {
+	cmd, err := d.selectQuery(ctx, table, target, "")
+	if err != nil {
+		return nil, fmt.Errorf("formatting query for %s: %w", table, err)
+	}
+	return d.StreamCtx(ctx, cmd)
+}
+
+// StreamTable returns an iterator over the target columns of table. Use "*" to select entire rows.
+func (d *DBIO) StreamTable(table, target string) (*RowIterator, error) {
+	return d.StreamTableCtx(context.Background(), table, target)
+}
+
+// Next advances to the next row. It returns false when there are no more rows or an error occurs (see Err).
+func (it *RowIterator) Next() bool {
+	if it.err != nil || !it.rows.Next() {
+		return false
+	}
+	it.row, it.nulls, it.err = it.scanner.scan(it.rows)
+	return it.err == nil
+}
+
+// Row returns the current row. The returned slice is not modified by later calls to Next.
+func (it *RowIterator) Row() []string {
+	return it.row
+}
+
+// IsNull returns true if the value at index i of the current row is NULL.
+func (it *RowIterator) IsNull(i int) bool {
+	return i >= 0 && i < len(it.nulls) && it.nulls[i]
+}
+
+// Err returns the first error encountered while reading rows.
+func (it *RowIterator) Err() error {
+	if it.err != nil {
+		return it.err
+	}
+	return it.rows.Err()
+}
+
+// Close releases the connection used by the iterator. It is safe to call more than once.
+func (it *RowIterator) Close() error {
+	return it.rows.Close()
+}
+
+// Each calls fn for each remaining row and closes the iterator. Iteration stops early if fn returns an error; ErrStop stops
+// iteration without returning an error.
+func (it *RowIterator) Each(fn func(row []string) error) error {
+	defer it.Close()
+	for it.Next() {
+		if err := fn(it.Row()); err != nil {
+			if errors.Is(err, ErrStop) {
+				return nil
+			}
+			return err
+		}
+	}
+	return it.Err()
+}
+
+// EachRowCtx calls fn with each row of the target columns of table without loading the table into memory. Use "*" to select
+// entire rows. Iteration stops early if fn returns an error; ErrStop stops iteration without returning an error.
+func (d *DBIO) EachRowCtx(ctx context.Context, table, target string, fn func(row []string) error) error {
+	it, err := d.StreamTableCtx(ctx, table, target)
+	if err != nil {
+		return err
+	}
+	if err = it.Each(fn); err != nil {
+		return fmt.Errorf("reading rows of %s: %w", table, err)
+	}
+	return nil
+}
+
+// EachRow calls fn with each row of the target columns of table without loading the table into memory.
+func (d *DBIO) EachRow(table, target string, fn func(row []string) error) error {
+	return d.EachRowCtx(context.Background(), table, target, fn)
+}
+
+// ExportTableCtx writes the contents of table to w as delimited text with a header row, one row at a time. sep is the field
+// delimiter (e.g. ',' or '\t'). NULL values are written as DBIO.NullValue.
+func (d *DBIO) ExportTableCtx(ctx context.Context, table string, w io.Writer, sep rune) error {
+	it, err := d.StreamTableCtx(ctx, table, "*")
+	if err != nil {
+		return err
+	}
+	out := csv.NewWriter(w)
+	out.Comma = sep
+	header := make([]string, len(it.Columns))
+	for i, c := range it.Columns {
+		header[i] = c.Name
+	}
+	if err = out.Write(header); err != nil {
+		it.Close()
+		return fmt.Errorf("exporting %s: %w", table, err)
+	}
+	if err = it.Each(out.Write); err != nil {
+		return fmt.Errorf("exporting %s: %w", table, err)
+	}
+	out.Flush()
+	if err = out.Error(); err != nil {
+		return fmt.Errorf("exporting %s: %w", table, err)
+	}
+	return nil
+}
+
+// ExportTable writes the contents of table to w as delimited text with a header row, one row at a time.
+func (d *DBIO) ExportTable(table string, w io.Writer, sep rune) error {
+	return d.ExportTableCtx(context.Background(), table, w, sep)
+}
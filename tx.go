@@ -0,0 +1,172 @@
+// Defines Tx struct and transaction functions
+
+package dbIO
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// querier is implemented by both *sql.DB and *sql.Tx.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Returns the transaction if one is in progress, or the database connection if not.
+func (d *DBIO) conn() querier {
+	if d.tx != nil {
+		return d.tx
+	}
+	return d.DB
+}
+
+// Tx is a database transaction. Any DBIO method called on a Tx (e.g. tx.UploadSlice, tx.UpdateColumnsE, tx.DeleteRowsE, tx.GetRowsE)
+// is executed within the transaction. Calling Begin or WithTx on a Tx creates a savepoint within the transaction.
+type Tx struct {
+	*DBIO
+	// Tx is the underlying sql transaction.
+	Tx        *sql.Tx
+	savepoint string
+	// savepoints counts the savepoints created within the transaction so each has a unique name. It is shared by nested Txs.
+	savepoints *int
+	done       bool
+}
+
+// Returns a new Tx wrapping a copy of d which is bound to tx.
+func newTx(d *DBIO, tx *sql.Tx) *Tx {
+	c := *d
+	c.tx = tx
+	return &Tx{DBIO: &c, Tx: tx, savepoints: new(int)}
+}
+
+// BeginTx starts a new transaction. The transaction is rolled back if ctx is cancelled before it is committed.
+// opts may be nil to use the default isolation level.
+func (d *DBIO) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := d.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	return newTx(d, tx), nil
+}
+
+// Begin starts a new transaction.
+func (d *DBIO) Begin() (*Tx, error) {
+	return d.BeginTx(context.Background(), nil)
+}
+
+// BeginTx creates a savepoint within the transaction and returns a nested Tx. Committing the nested Tx releases the savepoint
+// and rolling it back rolls the transaction back to the savepoint. The isolation level cannot be changed within a transaction,
+// so opts must be nil.
+func (t *Tx) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	if t.done {
+		return nil, sql.ErrTxDone
+	} else if opts != nil {
+		return nil, fmt.Errorf("%w: transaction options cannot be given for a savepoint", ErrInvalidQuery)
+	}
+	n := newTx(t.DBIO, t.Tx)
+	// Sibling savepoints must have different names, since MySQL replaces an existing savepoint with the same name
+	*t.savepoints++
+	n.savepoints = t.savepoints
+	n.savepoint = fmt.Sprintf("dbio_sp%d", *t.savepoints)
+	if _, err := t.Tx.ExecContext(ctx, "SAVEPOINT "+n.savepoint); err != nil {
+		return nil, fmt.Errorf("creating savepoint %s: %w", n.savepoint, err)
+	}
+	return n, nil
+}
+
+// Begin creates a savepoint within the transaction and returns a nested Tx.
+func (t *Tx) Begin() (*Tx, error) {
+	return t.BeginTx(context.Background(), nil)
+}
+
+// Commit commits the transaction, or releases the savepoint of a nested Tx.
+func (t *Tx) Commit() error {
+	if t.done {
+		return sql.ErrTxDone
+	}
+	t.done = true
+	if len(t.savepoint) > 0 {
+		if _, err := t.Tx.Exec("RELEASE SAVEPOINT " + t.savepoint); err != nil {
+			return fmt.Errorf("releasing savepoint %s: %w", t.savepoint, err)
+		}
+		return nil
+	}
+	return t.Tx.Commit()
+}
+
+// Rollback aborts the transaction, or rolls back to the savepoint of a nested Tx.
+func (t *Tx) Rollback() error {
+	if t.done {
+		return sql.ErrTxDone
+	}
+	t.done = true
+	if len(t.savepoint) > 0 {
+		if _, err := t.Tx.Exec("ROLLBACK TO SAVEPOINT " + t.savepoint); err != nil {
+			return fmt.Errorf("rolling back to savepoint %s: %w", t.savepoint, err)
+		}
+		return nil
+	}
+	return t.Tx.Rollback()
+}
+
+// Calls fn with t. Rolls back t if fn returns an error or panics, and commits t otherwise. Returns ctx's error if the transaction
+// was rolled back because ctx was cancelled.
+func runTx(ctx context.Context, t *Tx, fn func(*Tx) error) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			t.Rollback()
+			panic(p)
+		}
+	}()
+	if err = fn(t); err != nil {
+		if rerr := t.Rollback(); rerr != nil && !errors.Is(rerr, sql.ErrTxDone) {
+			err = errors.Join(err, rerr)
+		}
+		return err
+	}
+	if t.done {
+		// fn committed or rolled back the transaction itself
+		return nil
+	}
+	if err = t.Commit(); errors.Is(err, sql.ErrTxDone) && ctx.Err() != nil {
+		// database/sql rolled back the transaction when ctx was cancelled
+		err = fmt.Errorf("transaction rolled back: %w", ctx.Err())
+	}
+	return err
+}
+
+// WithTxCtx calls fn within a new transaction. The transaction is committed if fn returns nil, and is rolled back if fn returns
+// an error, panics, or ctx is cancelled.
+func (d *DBIO) WithTxCtx(ctx context.Context, fn func(tx *Tx) error) error {
+	t, err := d.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	return runTx(ctx, t, fn)
+}
+
+// WithTx calls fn within a new transaction. The transaction is committed if fn returns nil, and is rolled back if fn returns
+// an error or panics.
+func (d *DBIO) WithTx(fn func(tx *Tx) error) error {
+	return d.WithTxCtx(context.Background(), fn)
+}
+
+// WithTxCtx calls fn within a savepoint of the transaction. The savepoint is released if fn returns nil, and the transaction
+// is rolled back to the savepoint if fn returns an error or panics.
+func (t *Tx) WithTxCtx(ctx context.Context, fn func(tx *Tx) error) error {
+	n, err := t.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	return runTx(ctx, n, fn)
+}
+
+// WithTx calls fn within a savepoint of the transaction.
+func (t *Tx) WithTx(fn func(tx *Tx) error) error {
+	return t.WithTxCtx(context.Background(), fn)
+}
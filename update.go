@@ -3,87 +3,157 @@
 package dbIO
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 	"time"
 )
 
-// OptimizeTables calls optimize on all tables in the database.
-func (d *DBIO) OptimizeTables() {
+// OptimizeTablesCtx calls optimize on all tables in the database.
+func (d *DBIO) OptimizeTablesCtx(ctx context.Context) error {
+	var errs []error
 	for k := range d.Columns {
-		cmd, err := d.DB.Prepare(fmt.Sprintf("OPTIMIZE TABLE %s;", k))
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		cmd, err := d.conn().PrepareContext(ctx, fmt.Sprintf("OPTIMIZE TABLE %s;", quoteIdentifier(k)))
 		if err != nil {
-			d.logger.Printf("[Error] Formatting command to optimize table %s: %v\n", k, err)
-		} else {
-			cmd.Exec()
+			errs = append(errs, fmt.Errorf("formatting command to optimize table %s: %w", k, err))
+			continue
 		}
+		if _, err = cmd.ExecContext(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("optimizing table %s: %w", k, err))
+		}
+		cmd.Close()
+	}
+	return errors.Join(errs...)
+}
+
+// OptimizeTablesE calls optimize on all tables in the database.
+func (d *DBIO) OptimizeTablesE() error {
+	return d.OptimizeTablesCtx(context.Background())
+}
+
+// OptimizeTables calls optimize on all tables in the database.
+//
+// Deprecated: Use OptimizeTablesE, which returns errors instead of logging them.
+func (d *DBIO) OptimizeTables() {
+	d.logError(d.OptimizeTablesE())
+}
+
+// TruncateTableCtx clears all content from the given table.
+func (d *DBIO) TruncateTableCtx(ctx context.Context, table string) error {
+	tbl, err := d.checkTable(ctx, table)
+	if err != nil {
+		return fmt.Errorf("truncating table %s: %w", table, err)
 	}
+	cmd, err := d.conn().PrepareContext(ctx, fmt.Sprintf("TRUNCATE TABLE %s;", tbl))
+	if err != nil {
+		return fmt.Errorf("formatting command to truncate table %s: %w", table, err)
+	}
+	defer cmd.Close()
+	if _, err = cmd.ExecContext(ctx); err != nil {
+		return fmt.Errorf("truncating table %s: %w", table, err)
+	}
+	return nil
+}
+
+// TruncateTableE clears all content from the given table.
+func (d *DBIO) TruncateTableE(table string) error {
+	return d.TruncateTableCtx(context.Background(), table)
 }
 
 // TruncateTable clears all content from the given table.
+//
+// Deprecated: Use TruncateTableE, which returns errors instead of logging them.
 func (d *DBIO) TruncateTable(table string) {
-	cmd, err := d.DB.Prepare(fmt.Sprintf("TRUNCATE TABLE %s;", table))
-	if err != nil {
-		d.logger.Printf("[Error] Formatting command to truncate table %s: %v\n", table, err)
-	} else {
-		_, err = cmd.Exec()
+	d.logError(d.TruncateTableE(table))
+}
+
+// GetUpdateTimesCtx returns a map the last update date and time for each table.
+func (d *DBIO) GetUpdateTimesCtx(ctx context.Context) (map[string]time.Time, error) {
+	ret := make(map[string]time.Time)
+	for k := range d.Columns {
+		var v interface{}
+		cmd := "SELECT UPDATE_TIME FROM information_schema.tables WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?;"
+		err := d.retry(ctx, func() error {
+			return d.conn().QueryRowContext(ctx, cmd, d.Database, k).Scan(&v)
+		})
+		if err == sql.ErrNoRows || (err == nil && v == nil) {
+			// Update times are not recorded for all storage engines
+			continue
+		} else if err != nil {
+			return ret, fmt.Errorf("executing '%s': %w", cmd, err)
+		}
+		s := d.formatValue(v, "DATETIME")
+		t, err := time.Parse("2006-01-02 15:04:05", s)
 		if err != nil {
-			d.logger.Printf("[Error] Truncating table %s: %v\n", table, err)
+			return ret, fmt.Errorf("converting timestamp %s: %w", s, err)
 		}
+		ret[k] = t
 	}
+	return ret, nil
+}
+
+// GetUpdateTimesE returns a map the last update date and time for each table.
+func (d *DBIO) GetUpdateTimesE() (map[string]time.Time, error) {
+	return d.GetUpdateTimesCtx(context.Background())
 }
 
 // GetUpdateTimes returns a map the last update date and time for each table.
+//
+// Deprecated: Use GetUpdateTimesE, which returns errors instead of logging them.
 func (d *DBIO) GetUpdateTimes() map[string]time.Time {
-	ret := make(map[string]time.Time)
-	for k := range d.Columns {
-		cmd := fmt.Sprintf("SELECT UPDATE_TIME FROM information_schema.tables WHERE TABLE_SCHEMA = '%s' AND TABLE_NAME = '%s';", d.Database, k)
-		rows := d.Execute(cmd)
-		if len(rows) > 0 && !strings.Contains(rows[0][0], "<nil>") {
-			t, err := time.Parse("2006-01-02 15:04:05", rows[0][0])
-			if err == nil {
-				ret[k] = t
-			} else {
-				d.logger.Printf("[Error] Converting timestamp %s: %v\n", rows[0][0], err)
-			}
-		}
-	}
+	ret, err := d.GetUpdateTimesE()
+	d.logError(err)
 	return ret
 }
 
-// LastUpdate returns the time of the most recent update.
-func (d *DBIO) LastUpdate() time.Time {
+// LastUpdateCtx returns the time of the most recent update.
+func (d *DBIO) LastUpdateCtx(ctx context.Context) (time.Time, error) {
 	var ret time.Time
-	t := d.GetUpdateTimes()
+	t, err := d.GetUpdateTimesCtx(ctx)
 	for _, v := range t {
 		if v.After(ret) {
 			ret = v
 		}
 	}
+	return ret, err
+}
+
+// LastUpdateE returns the time of the most recent update.
+func (d *DBIO) LastUpdateE() (time.Time, error) {
+	return d.LastUpdateCtx(context.Background())
+}
+
+// LastUpdate returns the time of the most recent update.
+//
+// Deprecated: Use LastUpdateE, which returns errors instead of logging them.
+func (d *DBIO) LastUpdate() time.Time {
+	ret, err := d.LastUpdateE()
+	d.logError(err)
 	return ret
 }
 
-// Submits update command and returns true if successful
-func (d *DBIO) update(table, command string) bool {
-	ret := true
-	cmd, err := d.DB.Prepare(command)
-	if err != nil {
-		d.logger.Printf("[Error] Preparing update for %s: %v\n", table, err)
-		ret = false
-	} else {
-		_, err = cmd.Exec()
-		cmd.Close()
+// Submits update command
+func (d *DBIO) update(ctx context.Context, table, command string) error {
+	return d.retry(ctx, func() error {
+		cmd, err := d.conn().PrepareContext(ctx, command)
 		if err != nil {
-			d.logger.Printf("[Error] Updating row(s) from %s: %v\n", table, err)
-			ret = false
+			return newError("preparing update for", table, err)
 		}
-	}
-	return ret
+		_, err = cmd.ExecContext(ctx)
+		cmd.Close()
+		return newError("updating row(s) from", table, err)
+	})
 }
 
-// UpdateColumns updates columns (specified as outer map key) in table where column == inner map key with map values. Returns true if successful.
-func (d *DBIO) UpdateColumns(table, idcol string, values map[string]map[string]string) bool {
+// UpdateColumnsCtx updates columns (specified as outer map key) in table where column == inner map key with map values.
+func (d *DBIO) UpdateColumnsCtx(ctx context.Context, table, idcol string, values map[string]map[string]string) error {
 	var cmd strings.Builder
 	first := true
 	cmd.WriteString(fmt.Sprintf("UPDATE %s SET", table))
@@ -100,36 +170,63 @@ func (d *DBIO) UpdateColumns(table, idcol string, values map[string]map[string]s
 		first = false
 	}
 	cmd.WriteString(fmt.Sprintf("\nWHERE %s IS NOT NULL;", idcol))
-	return d.update(table, cmd.String())
+	return d.update(ctx, table, cmd.String())
 }
 
-// UpdateRow updates a single column in the given table and returns true if successful.
-func (d *DBIO) UpdateRow(table, target, value, column, op, key string) bool {
+// UpdateColumnsE updates columns (specified as outer map key) in table where column == inner map key with map values.
+func (d *DBIO) UpdateColumnsE(table, idcol string, values map[string]map[string]string) error {
+	return d.UpdateColumnsCtx(context.Background(), table, idcol, values)
+}
+
+// UpdateColumns updates columns (specified as outer map key) in table where column == inner map key with map values. Returns true if successful.
+//
+// Deprecated: Use UpdateColumnsE, which returns errors instead of logging them.
+func (d *DBIO) UpdateColumns(table, idcol string, values map[string]map[string]string) bool {
+	err := d.UpdateColumnsE(table, idcol, values)
+	d.logError(err)
+	return err == nil
+}
+
+// UpdateRowCtx updates a single column in the given table.
+func (d *DBIO) UpdateRowCtx(ctx context.Context, table, target, value, column, op, key string) error {
 	if _, err := strconv.ParseFloat(value, 64); err != nil {
 		value = fmt.Sprintf("'%s'", value)
 	}
 	if _, err := strconv.ParseFloat(key, 64); err != nil {
 		key = fmt.Sprintf("'%s'", key)
 	}
-	return d.update(table, fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s %s %s;", table, target, value, column, op, key))
+	return d.update(ctx, table, fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s %s %s;", table, target, value, column, op, key))
+}
+
+// UpdateRowE updates a single column in the given table.
+func (d *DBIO) UpdateRowE(table, target, value, column, op, key string) error {
+	return d.UpdateRowCtx(context.Background(), table, target, value, column, op, key)
+}
+
+// UpdateRow updates a single column in the given table and returns true if successful.
+//
+// Deprecated: Use UpdateRowE, which returns errors instead of logging them.
+func (d *DBIO) UpdateRow(table, target, value, column, op, key string) bool {
+	err := d.UpdateRowE(table, target, value, column, op, key)
+	d.logError(err)
+	return err == nil
 }
 
 // Performs given deletion command
-func (d *DBIO) deleteEntries(table, command string) {
-	cmd, err := d.DB.Prepare(command)
-	if err != nil {
-		d.logger.Printf("[Error] Preparing deletion from %s: %v\n", table, err)
-	} else {
-		_, err = cmd.Exec()
-		cmd.Close()
+func (d *DBIO) deleteEntries(ctx context.Context, table, command string) error {
+	return d.retry(ctx, func() error {
+		cmd, err := d.conn().PrepareContext(ctx, command)
 		if err != nil {
-			d.logger.Printf("[Error] Deleting row(s) from %s: %v\n", table, err)
+			return newError("preparing deletion from", table, err)
 		}
-	}
+		_, err = cmd.ExecContext(ctx)
+		cmd.Close()
+		return newError("deleting row(s) from", table, err)
+	})
 }
 
-// DeleteRows deletes rows from the database if the value in the given column is contained in the values slice.
-func (d *DBIO) DeleteRows(table, column string, values []string) {
+// DeleteRowsCtx deletes rows from the database if the value in the given column is contained in the values slice.
+func (d *DBIO) DeleteRowsCtx(ctx context.Context, table, column string, values []string) error {
 	var b strings.Builder
 	first := true
 	for _, i := range values {
@@ -141,10 +238,34 @@ func (d *DBIO) DeleteRows(table, column string, values []string) {
 		b.WriteByte('\'')
 		first = false
 	}
-	d.deleteEntries(table, fmt.Sprintf("DELETE FROM %s WHERE %s IN (%s);", table, column, b.String()))
+	return d.deleteEntries(ctx, table, fmt.Sprintf("DELETE FROM %s WHERE %s IN (%s);", table, column, b.String()))
+}
+
+// DeleteRowsE deletes rows from the database if the value in the given column is contained in the values slice.
+func (d *DBIO) DeleteRowsE(table, column string, values []string) error {
+	return d.DeleteRowsCtx(context.Background(), table, column, values)
+}
+
+// DeleteRows deletes rows from the database if the value in the given column is contained in the values slice.
+//
+// Deprecated: Use DeleteRowsE, which returns errors instead of logging them.
+func (d *DBIO) DeleteRows(table, column string, values []string) {
+	d.logError(d.DeleteRowsE(table, column, values))
+}
+
+// DeleteRowCtx deletes a single row from the database where the value in the given column equals value.
+func (d *DBIO) DeleteRowCtx(ctx context.Context, table, column, value string) error {
+	return d.deleteEntries(ctx, table, fmt.Sprintf("DELETE FROM %s WHERE %s = '%s';", table, column, value))
+}
+
+// DeleteRowE deletes a single row from the database where the value in the given column equals value.
+func (d *DBIO) DeleteRowE(table, column, value string) error {
+	return d.DeleteRowCtx(context.Background(), table, column, value)
 }
 
 // DeleteRow deletes a single row from the database where the value in the given column equals value.
+//
+// Deprecated: Use DeleteRowE, which returns errors instead of logging them.
 func (d *DBIO) DeleteRow(table, column, value string) {
-	d.deleteEntries(table, fmt.Sprintf("DELETE FROM %s WHERE %s = '%s';", table, column, value))
+	d.logError(d.DeleteRowE(table, column, value))
 }
@@ -5,78 +5,88 @@ package dbIO
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"math"
 	"os"
 	"strconv"
 	"strings"
 	"unicode/utf8"
 )
 
-// Insert executes the given INSERT command
+// Returned by ReadColumnsE when the last statement in a schema file has no terminating semicolon.
+var errUnterminated = errors.New("unterminated statement")
+
+// Executes the given INSERT command
+func (d *DBIO) insert(ctx context.Context, table, command string) error {
+	return d.retry(ctx, func() error {
+		cmd, err := d.conn().PrepareContext(ctx, command)
+		if err != nil {
+			return newError("formatting command for upload to", table, err)
+		}
+		_, err = cmd.ExecContext(ctx)
+		cmd.Close()
+		return newError("uploading to", table, err)
+	})
+}
+
+// InsertCtx executes the given INSERT command.
+func (d *DBIO) InsertCtx(ctx context.Context, table, command string) error {
+	return d.insert(ctx, table, command)
+}
+
+// Insert executes the given INSERT command. Errors are logged as well as returned.
 func (d *DBIO) Insert(table, command string) error {
-	var err error
-	cmd, err := d.DB.Prepare(command)
-	if err != nil {
-		d.logger.Printf("[Error] Formatting command for upload to %s: %v\n", table, err)
-	}
-	_, err = cmd.Exec()
-	cmd.Close()
-	if err != nil {
-		d.logger.Printf("[Error] Uploading to %s: %v\n", table, err)
-	}
+	err := d.insert(context.Background(), table, command)
+	d.logError(err)
 	return err
 }
 
-func getDenominator(list [][]string) int {
-	// Returns denominator for subsetting upload slice (size in bytes / 16Mb)
-	max := 10000000.0
-	size := 0
-	for _, i := range list {
-		for _, j := range i {
-			size += len([]byte(j))
-		}
+// UploadSliceCtx uploads a two-dimensional string slice to table using parameterized INSERT statements (see InsertRows), split into
+// chunks if it exceeds SQL size limits. If ctx is cancelled, the upload stops before the next chunk and the context's error is
+// returned. If DBIO.Retry is set, a chunk which fails with a transient error is retried before continuing with the next chunk, so
+// rows which were already uploaded are not resubmitted.
+func (d *DBIO) UploadSliceCtx(ctx context.Context, table string, values [][]string) error {
+	if len(values) == 0 {
+		return nil
 	}
-	return int(math.Ceil(float64(size*8) / max))
+	_, err := d.insertRows(ctx, table, values, UploadOptions{}, func(n int) {
+		fmt.Printf("\r\tUploaded %d of %d rows to %s.", n, len(values), table)
+	})
+	fmt.Println()
+	return err
 }
 
-// UploadSlice formats two-dimensional string slice for upload to database and splits uploads into chunks if it exceeds SQL size limit.
+// UploadSlice uploads a two-dimensional string slice to table, split into chunks if it exceeds SQL size limits.
 func (d *DBIO) UploadSlice(table string, values [][]string) error {
-	var err error
-	if len(values) > 0 {
-		// Upload in chunks
-		idx := len(values) / getDenominator(values)
-		var start, end int
-		for start < len(values) {
-			// Advance indeces
-			end = start + idx
-			if end > len(values) {
-				// Get last less than idx rows
-				end = len(values)
-			}
-			vals, _ := FormatSlice(values[start:end])
-			err = d.Insert(table, fmt.Sprintf("INSERT INTO %s (%s) VALUES %s;", table, d.Columns[table], vals))
-			if err == nil {
-				fmt.Printf("\r\tUploaded %d of %d rows to %s.", end, len(values), table)
-			} else {
-				break
-			}
-			start += idx
-		}
-		fmt.Println()
+	return d.UploadSliceCtx(context.Background(), table, values)
+}
+
+// UpdateDBCtx adds new rows to table. Values must be formatted using FormatMap or FormatSlice.
+func (d *DBIO) UpdateDBCtx(ctx context.Context, table, values string, l int) error {
+	cmd := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s;", table, d.Columns[table], values)
+	if err := d.insert(ctx, table, cmd); err != nil {
+		return err
 	}
-	return err
+	fmt.Printf("\tUploaded %d rows to %s.\n", l, table)
+	return nil
+}
+
+// UpdateDBE adds new rows to table. Values must be formatted using FormatMap or FormatSlice.
+func (d *DBIO) UpdateDBE(table, values string, l int) error {
+	return d.UpdateDBCtx(context.Background(), table, values, l)
 }
 
 // UpdateDB adds new rows to table. Values must be formatted using FormatMap or FormatSlice.
+// Returns 1 if successful and 0 if not, so results can be tallied.
+//
+// Deprecated: Use UpdateDBE, which returns errors instead of logging them.
 func (d *DBIO) UpdateDB(table, values string, l int) int {
-	cmd := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s;", table, d.Columns[table], values)
-	err := d.Insert(table, cmd)
-	if err != nil {
+	if err := d.UpdateDBE(table, values, l); err != nil {
+		d.logError(err)
 		return 0
 	}
-	fmt.Printf("\tUploaded %d rows to %s.\n", l, table)
 	return 1
 }
 
@@ -174,44 +184,53 @@ func FormatSlice(data [][]string) (string, int) {
 }
 
 // Converts sql query result to map of strings.
-func (d *DBIO) columnMap(rows *sql.Rows) {
-	columns, _ := rows.Columns()
-	count := len(columns)
-	values := make([]interface{}, count)
-	pointers := make([]interface{}, count)
+func (d *DBIO) columnMap(rows *sql.Rows) error {
 	for rows.Next() {
-		for i := range columns {
-			pointers[i] = &values[i]
+		var k, v string
+		if err := rows.Scan(&k, &v); err != nil {
+			return err
 		}
-		// Maps items to values via pointers
-		rows.Scan(pointers...)
-		// Use Sprintf to convert interface to string
-		k := fmt.Sprintf("%s", values[0])
-		v := fmt.Sprintf("%s", values[1])
 		d.Columns[k] = v
 	}
+	return rows.Err()
 }
 
-// GetTableColumns extracts table and column names from the database and stores them in the Columns map.
-func (d *DBIO) GetTableColumns() {
+// GetTableColumnsCtx extracts table and column names from the database and stores them in the Columns map.
+func (d *DBIO) GetTableColumnsCtx(ctx context.Context) error {
 	d.Columns = make(map[string]string)
 	cmd := `SELECT table_name,GROUP_CONCAT(column_name ORDER BY ordinal_position) FROM information_schema.columns 
 WHERE table_schema = DATABASE() GROUP BY table_name ORDER BY table_name;`
-	rows, err := d.DB.Query(cmd)
+	rows, err := d.conn().QueryContext(ctx, cmd)
 	if err != nil {
-		d.logger.Printf("[ERROR] Extracting table and column names: %v\n\n", err)
+		return fmt.Errorf("extracting table and column names: %w", err)
 	}
 	defer rows.Close()
-	d.columnMap(rows)
+	if err = d.columnMap(rows); err != nil {
+		return fmt.Errorf("reading table and column names: %w", err)
+	}
+	return nil
 }
 
-// ReadColumns builds a map of column statements with types from infile. See README for infile formatting.
-func (d *DBIO) ReadColumns(infile string) []string {
+// GetTableColumnsE extracts table and column names from the database and stores them in the Columns map.
+func (d *DBIO) GetTableColumnsE() error {
+	return d.GetTableColumnsCtx(context.Background())
+}
+
+// GetTableColumns extracts table and column names from the database and stores them in the Columns map.
+//
+// Deprecated: Use GetTableColumnsE, which returns errors instead of logging them.
+func (d *DBIO) GetTableColumns() {
+	d.logError(d.GetTableColumnsE())
+}
+
+// ReadColumnsE builds a map of column statements with types from infile. See README for infile formatting.
+// Returns an error wrapping ErrSchemaParse if the final statement is not terminated with a semicolon.
+func (d *DBIO) ReadColumnsE(infile string) ([]string, error) {
 	var ret []string
 	var table string
 	f, err := os.Open(infile)
 	if err != nil {
-		d.logger.Fatalf("[ERROR] Reading %s: %v\n\n", infile, err)
+		return nil, fmt.Errorf("reading %s: %w", infile, err)
 	}
 	defer f.Close()
 	input := bufio.NewScanner(f)
@@ -225,25 +244,75 @@ func (d *DBIO) ReadColumns(infile string) []string {
 			}
 		}
 	}
+	if err = input.Err(); err != nil {
+		return ret, fmt.Errorf("reading %s: %w", infile, err)
+	}
+	if len(strings.TrimSpace(table)) > 0 {
+		return ret, fmt.Errorf("%w: %w in %s: %s", ErrSchemaParse, errUnterminated, infile, strings.TrimSpace(table))
+	}
+	return ret, nil
+}
+
+// ReadColumns builds a map of column statements with types from infile. See README for infile formatting.
+//
+// Deprecated: Use ReadColumnsE, which returns errors instead of exiting. As before, an unterminated statement at the end of
+// infile is ignored.
+func (d *DBIO) ReadColumns(infile string) []string {
+	ret, err := d.ReadColumnsE(infile)
+	if err != nil && !errors.Is(err, errUnterminated) {
+		d.logger.Fatalf("[ERROR] %v\n\n", err)
+	}
 	return ret
 }
 
-// NewTables executes new table commands from infile. See README for infile formatting.
-func (d *DBIO) NewTables(infile string) {
+// Returns the start of a statement for logging.
+func statementPrefix(s string) string {
+	if len(s) > 20 {
+		return s[:20]
+	}
+	return s
+}
+
+// NewTablesCtx executes new table commands from infile. See README for infile formatting.
+// Errors from executing statements wrap ErrSchemaParse.
+func (d *DBIO) NewTablesCtx(ctx context.Context, infile string) error {
+	tables, err := d.ReadColumnsE(infile)
+	if err != nil {
+		return err
+	}
+	return d.createTables(ctx, tables)
+}
+
+// Executes the given table commands and updates Columns.
+func (d *DBIO) createTables(ctx context.Context, tables []string) error {
 	fmt.Println("\n\tInitializing new tables...")
-	for _, i := range d.ReadColumns(infile) {
-		// Table name is last word before (
-		cmd, err := d.DB.Prepare(i)
+	for _, i := range tables {
+		cmd, err := d.conn().PrepareContext(ctx, i)
 		if err != nil {
-			d.logger.Fatalf("[Error] Formatting command %s. %v\n\n", i, err)
+			return fmt.Errorf("%w: formatting command %s: %w", ErrSchemaParse, i, err)
 		}
-		_, err = cmd.Exec()
+		_, err = cmd.ExecContext(ctx)
+		cmd.Close()
 		if err != nil {
-			d.logger.Println(i)
-			d.logger.Fatalf("[Error] Executing %s. %v\n\n", i, err)
-		} else {
-			d.logger.Printf("Successfully executed %s...\n", i[:20])
+			return fmt.Errorf("%w: executing %s: %w", ErrSchemaParse, i, err)
 		}
+		d.logger.Printf("Successfully executed %s...\n", statementPrefix(i))
+	}
+	return d.GetTableColumnsCtx(ctx)
+}
+
+// NewTablesE executes new table commands from infile. See README for infile formatting.
+// Errors from executing statements wrap ErrSchemaParse.
+func (d *DBIO) NewTablesE(infile string) error {
+	return d.NewTablesCtx(context.Background(), infile)
+}
+
+// NewTables executes new table commands from infile. See README for infile formatting.
+//
+// Deprecated: Use NewTablesE, which returns errors instead of exiting. As before, an unterminated statement at the end of infile
+// is ignored.
+func (d *DBIO) NewTables(infile string) {
+	if err := d.createTables(context.Background(), d.ReadColumns(infile)); err != nil {
+		d.logger.Fatalf("[Error] %v\n\n", err)
 	}
-	d.GetTableColumns()
 }
@@ -0,0 +1,112 @@
+// Contains functions for converting query results to strings
+
+package dbIO
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// NullNA renders NULL values as NA.
+	NullNA = "NA"
+	// NullSentinel renders NULL values as \N, which is how mysqldump and LOAD DATA represent NULL.
+	NullSentinel = `\N`
+)
+
+// Returns the string form of a BIT value.
+func formatBits(b []byte) string {
+	var ret uint64
+	for _, i := range b {
+		ret = ret<<8 | uint64(i)
+	}
+	return strconv.FormatUint(ret, 10)
+}
+
+// Returns the string form of a date or time value in MySQL's format.
+func formatTime(t time.Time, dbtype string) string {
+	if dbtype == "DATE" {
+		if t.IsZero() {
+			return "0000-00-00"
+		}
+		return t.Format("2006-01-02")
+	} else if t.IsZero() {
+		return "0000-00-00 00:00:00"
+	} else if t.Nanosecond() > 0 {
+		return strings.TrimRight(t.Format("2006-01-02 15:04:05.000000"), "0")
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+// Returns the string form of a value returned by the driver for a column of the given database type. NULL values are
+// returned as DBIO.NullValue.
+func (d *DBIO) formatValue(v interface{}, dbtype string) string {
+	switch x := v.(type) {
+	case nil:
+		return d.NullValue
+	case []byte:
+		if dbtype == "BIT" {
+			return formatBits(x)
+		}
+		return string(x)
+	case string:
+		return x
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case uint64:
+		return strconv.FormatUint(x, 10)
+	case float32:
+		return strconv.FormatFloat(float64(x), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case bool:
+		if x {
+			return "1"
+		}
+		return "0"
+	case time.Time:
+		return formatTime(x, dbtype)
+	}
+	return fmt.Sprint(v)
+}
+
+// rowScanner converts rows to strings using the column types of a result.
+type rowScanner struct {
+	d        *DBIO
+	columns  []Column
+	values   []interface{}
+	pointers []interface{}
+}
+
+// Returns a rowScanner for rows.
+func (d *DBIO) newRowScanner(rows *sql.Rows) (*rowScanner, error) {
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	ret := &rowScanner{d: d, columns: make([]Column, len(types)), values: make([]interface{}, len(types)), pointers: make([]interface{}, len(types))}
+	for i, t := range types {
+		nullable, ok := t.Nullable()
+		ret.columns[i] = Column{Name: t.Name(), Type: t.DatabaseTypeName(), Nullable: nullable || !ok}
+		ret.pointers[i] = &ret.values[i]
+	}
+	return ret, nil
+}
+
+// Returns the current row as strings, and whether each value is NULL.
+func (s *rowScanner) scan(rows *sql.Rows) ([]string, []bool, error) {
+	// Maps items to values via pointers
+	if err := rows.Scan(s.pointers...); err != nil {
+		return nil, nil, err
+	}
+	ret := make([]string, len(s.values))
+	nulls := make([]bool, len(s.values))
+	for i, v := range s.values {
+		ret[i] = s.d.formatValue(v, s.columns[i].Type)
+		nulls[i] = v == nil
+	}
+	return ret, nulls, nil
+}